@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+)
+
+// The fields identifying the documents of each resource
+var (
+	userKeys      = []audit.Key{{Field: "username", Var: "username"}}
+	classroomKeys = []audit.Key{{Field: "building", Var: "building"}, {Field: "room", Var: "room"}}
+	buildingKeys  = []audit.Key{{Field: "code", Var: "code"}}
+	courseKeys    = []audit.Key{{Field: "shorthand", Var: "courseShortHand"}}
+	termKeys      = []audit.Key{{Field: "year", Var: "year", Int: true}, {Field: "term", Var: "term"}}
+	blockKeys     = []audit.Key{{Field: "code", Var: "code"}}
+	scheduleKeys  = []audit.Key{{Field: "year", Var: "year", Int: true}, {Field: "terms.term", Var: "term", Body: "term"}}
+)
+
+// auditedRoutes lists every route that changes data, by method and path. The route test fails if
+// a POST, PUT, PATCH or DELETE route is missing, other than logging in and out, unlocking and
+// password resets, which record their own security events, and marking a notification read.
+var auditedRoutes = map[string]audit.Target{
+	"POST /users":                       {Action: "create", ResourceType: "user", Collection: "users", Keys: userKeys},
+	"PUT /users/{username}":             {Action: "replace", ResourceType: "user", Collection: "users", Keys: userKeys},
+	"PATCH /users/{username}":           {Action: "patch", ResourceType: "user", Collection: "users", Keys: userKeys},
+	"DELETE /users/{username}":          {Action: "deactivate", ResourceType: "user", Collection: "users", Keys: userKeys},
+	"POST /users/{username}/reactivate": {Action: "reactivate", ResourceType: "user", Collection: "users", Keys: userKeys},
+	"PUT /users/{username}/admin":       {Action: "grant_admin", ResourceType: "user", Collection: "users", Keys: userKeys},
+	"DELETE /users/{username}/admin":    {Action: "revoke_admin", ResourceType: "user", Collection: "users", Keys: userKeys},
+
+	"PUT /users/{username}/notification-preferences": {Action: "replace", ResourceType: "notification_preferences", Collection: "notification_preferences", Keys: userKeys},
+
+	"POST /classrooms":                          {Action: "create", ResourceType: "classroom", Collection: "classrooms", Keys: classroomKeys},
+	"PUT /classrooms/{building}/{room}":         {Action: "replace", ResourceType: "classroom", Collection: "classrooms", Keys: classroomKeys},
+	"PATCH /classrooms/{building}/{room}":       {Action: "patch", ResourceType: "classroom", Collection: "classrooms", Keys: classroomKeys},
+	"DELETE /classrooms/{building}/{room}":      {Action: "delete", ResourceType: "classroom", Collection: "classrooms", Keys: classroomKeys},
+	"POST /classrooms/{building}/{room}/rename": {Action: "rename", ResourceType: "classroom", Collection: "classrooms", Keys: classroomKeys},
+
+	"POST /buildings":          {Action: "create", ResourceType: "building", Collection: "buildings", Keys: buildingKeys},
+	"PUT /buildings/{code}":    {Action: "replace", ResourceType: "building", Collection: "buildings", Keys: buildingKeys},
+	"DELETE /buildings/{code}": {Action: "delete", ResourceType: "building", Collection: "buildings", Keys: buildingKeys},
+
+	"POST /courses":                     {Action: "create", ResourceType: "course", Collection: "courses", Keys: courseKeys},
+	"PUT /courses/{courseShortHand}":    {Action: "replace", ResourceType: "course", Collection: "courses", Keys: courseKeys},
+	"PATCH /courses/{courseShortHand}":  {Action: "patch", ResourceType: "course", Collection: "courses", Keys: courseKeys},
+	"DELETE /courses/{courseShortHand}": {Action: "delete", ResourceType: "course", Collection: "courses", Keys: courseKeys},
+
+	"POST /terms":                 {Action: "create", ResourceType: "term", Collection: "terms", Keys: termKeys},
+	"PUT /terms/{year}/{term}":    {Action: "replace", ResourceType: "term", Collection: "terms", Keys: termKeys},
+	"DELETE /terms/{year}/{term}": {Action: "delete", ResourceType: "term", Collection: "terms", Keys: termKeys},
+
+	"POST /blocks":          {Action: "create", ResourceType: "block", Collection: "blocks", Keys: blockKeys},
+	"PUT /blocks/{code}":    {Action: "replace", ResourceType: "block", Collection: "blocks", Keys: blockKeys},
+	"DELETE /blocks/{code}": {Action: "delete", ResourceType: "block", Collection: "blocks", Keys: blockKeys},
+
+	"POST /schedules/{year}/{term}/generate": {Action: "generate", ResourceType: "schedule", Collection: "draft_schedules", Keys: scheduleKeys},
+	"PUT /schedules/{year}/{term}":           {Action: "replace", ResourceType: "schedule", Collection: "draft_schedules", Keys: scheduleKeys},
+	"PATCH /schedules/{year}/{term}":         {Action: "patch", ResourceType: "schedule", Collection: "draft_schedules", Keys: scheduleKeys},
+	"POST /schedules/prev":                   {Action: "approve", ResourceType: "schedule", Collection: "draft_schedules", AfterCollection: "previous_schedules", Keys: scheduleKeys},
+}
+
+// recordChanges adds every change made through the router to the audit log
+func recordChanges(router *mux.Router) {
+	router.Use(audit.Record(client.Database("schedule_db"), auditedRoutes))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/openapi"
+)
+
+// TestAuditedRoutes fails when a route that changes data isn't recorded in the audit log
+func TestAuditedRoutes(t *testing.T) {
+	// Unlocking and resetting a password record their own security events, reading a
+	// notification changes nothing worth auditing
+	unaudited := map[string]bool{
+		"POST /login": true, "POST /logout": true, "POST /users/{username}/unlock": true,
+		"POST /password/forgot": true, "POST /password/reset": true,
+		"POST /users/{username}/notifications/{id}/read": true,
+	}
+
+	described := map[string]bool{}
+	for _, op := range openapi.Operations {
+		route := op.Method + " " + op.Path
+		described[route] = true
+		if op.Method == http.MethodGet || unaudited[route] {
+			continue
+		}
+		if _, ok := auditedRoutes[route]; !ok {
+			t.Errorf("Route %s changes data but is missing from auditedRoutes", route)
+		}
+	}
+	for route := range auditedRoutes {
+		if !described[route] {
+			t.Errorf("Route %s is in auditedRoutes but not registered", route)
+		}
+	}
+}
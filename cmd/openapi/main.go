@@ -0,0 +1,28 @@
+// Command openapi writes the OpenAPI document generated from the routes and Go types of the
+// backend. Run it with go generate ./modules/openapi after changing a route or a struct.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/openapi"
+)
+
+func main() {
+	output := flag.String("o", "modules/openapi/openapi.json", "file to write the document to")
+	flag.Parse()
+
+	doc, err := openapi.Generate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error generating the OpenAPI document:", err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(*output, doc, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error writing the OpenAPI document:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// demo.json holds a small data set for trying the backend out locally
+//
+//go:embed demo.json
+var demoData []byte
+
+// dataSet is the file format used by seed, import and export
+type dataSet struct {
+	Courses    []courses.Course       `json:"courses,omitempty"`
+	Classrooms []classrooms.Classroom `json:"classrooms,omitempty"`
+	Users      []users.User           `json:"users,omitempty"`
+}
+
+// importSummary counts what an import or seed changed
+type importSummary struct {
+	Resource string `json:"resource"`
+	Inserted int64  `json:"inserted"`
+	Updated  int64  `json:"updated"`
+}
+
+// seedCommand inserts the demo data, leaving any existing documents untouched
+func seedCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	var data dataSet
+	err := json.Unmarshal(demoData, &data)
+	if err != nil {
+		return err
+	}
+
+	var summaries []importSummary
+	for _, resource := range []string{"courses", "classrooms", "users"} {
+		summary, err := upsertResource(ctx, a, resource, data, false)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	var lines []string
+	for _, summary := range summaries {
+		lines = append(lines, fmt.Sprintf("%s: %d inserted", summary.Resource, summary.Inserted))
+	}
+	a.out.result(strings.Join(lines, "\n"), summaries)
+	return nil
+}
+
+// importCommand reads a JSON array of courses, classrooms or users and upserts it
+func importCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	resource := args[0]
+
+	file, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	var data dataSet
+	switch resource {
+	case "courses":
+		data.Courses, err = decodeCourses(file)
+	case "classrooms":
+		err = json.Unmarshal(file, &data.Classrooms)
+	case "users":
+		err = json.Unmarshal(file, &data.Users)
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return fmt.Errorf("error decoding %s: %w", args[1], err)
+	}
+
+	summary, err := upsertResource(ctx, a, resource, data, true)
+	if err != nil {
+		return err
+	}
+
+	a.out.result(fmt.Sprintf("%s: %d inserted, %d updated", summary.Resource, summary.Inserted, summary.Updated), summary)
+	return nil
+}
+
+// exportCommand writes every course, classroom or user as a JSON array, to a file or the output
+func exportCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	resource := args[0]
+
+	var items interface{}
+	var err error
+	switch resource {
+	case "courses":
+		var list []courses.Course
+		err = findAll(ctx, a.db.Collection("courses"), &list)
+		items = list
+	case "classrooms":
+		var list []classrooms.Classroom
+		err = findAll(ctx, a.db.Collection("classrooms"), &list)
+		items = list
+	case "users":
+		var list []users.User
+		err = findAll(ctx, a.db.Collection("users"), &list)
+		// Never export password hashes
+		for i := range list {
+			list[i].Password = ""
+		}
+		items = list
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	w := a.out.w
+	if len(args) == 2 {
+		file, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(items)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		a.out.result(fmt.Sprintf("exported %s to %s", resource, args[1]), map[string]string{"resource": resource, "file": args[1]})
+	}
+	return nil
+}
+
+// upsertResource writes one resource of the data set. When overwrite is false existing
+// documents are left as they are, which is what seeding wants.
+func upsertResource(ctx context.Context, a *app, resource string, data dataSet, overwrite bool) (importSummary, error) {
+	summary := importSummary{Resource: resource}
+	collection := a.db.Collection(resource)
+
+	write := func(filter bson.M, document interface{}, onInsert bson.M) error {
+		fields, err := toBSON(document)
+		if err != nil {
+			return err
+		}
+		for key := range onInsert {
+			delete(fields, key)
+		}
+		// Every resource written here is versioned, overwriting one moves it to the next version
+		delete(fields, etag.Field)
+
+		update := bson.M{}
+		if overwrite {
+			update["$set"] = fields
+			update["$inc"] = bson.M{etag.Field: 1}
+			if len(onInsert) > 0 {
+				update["$setOnInsert"] = onInsert
+			}
+		} else {
+			for key, value := range onInsert {
+				fields[key] = value
+			}
+			fields[etag.Field] = 1
+			update["$setOnInsert"] = fields
+		}
+
+		res, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+		summary.Inserted += res.UpsertedCount
+		if res.UpsertedCount == 0 {
+			summary.Updated += res.ModifiedCount
+		}
+		return nil
+	}
+
+	var err error
+	switch resource {
+	case "courses":
+		for _, course := range data.Courses {
+			if problems := course.Validate(); len(problems) > 0 {
+				err = fmt.Errorf("invalid course %s: %s", course.ShortHand, strings.Join(problems, "; "))
+				break
+			}
+			err = write(bson.M{"shorthand": course.ShortHand}, course, nil)
+			if err != nil {
+				break
+			}
+		}
+	case "classrooms":
+		for _, classroom := range data.Classrooms {
+			if problems := classroom.Validate(); len(problems) > 0 {
+				err = fmt.Errorf("invalid classroom %s %s: %s", classroom.Building, classroom.Room_number, strings.Join(problems, "; "))
+				break
+			}
+			err = write(bson.M{"building": classroom.Building, "room": classroom.Room_number}, classroom, nil)
+			if err != nil {
+				break
+			}
+		}
+	case "users":
+		for _, user := range data.Users {
+			// Accept plain text passwords in the file, bcrypt hashes are kept as they are
+			if user.Password != "" && !strings.HasPrefix(user.Password, "$2") {
+				user.Password, err = users.HashPassword(user.Password)
+				if err != nil {
+					break
+				}
+			}
+			// Admin rights are only ever granted with "admin promote"
+			err = write(bson.M{"username": user.Username}, user, bson.M{"isAdmin": false})
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return summary, fmt.Errorf("error writing %s: %w", resource, err)
+	}
+	return summary, nil
+}
+
+// decodeCourses reads a JSON array of courses, filling in the defaults for fields a course leaves out
+func decodeCourses(file []byte) ([]courses.Course, error) {
+	var raw []json.RawMessage
+	err := json.Unmarshal(file, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]courses.Course, len(raw))
+	for i := range raw {
+		list[i] = courses.NewCourse()
+		err = json.Unmarshal(raw[i], &list[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// toBSON converts a struct to a document using its bson tags
+func toBSON(value interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var document bson.M
+	err = bson.Unmarshal(raw, &document)
+	return document, err
+}
+
+// findAll decodes every document in the collection into results
+func findAll(ctx context.Context, collection *mongo.Collection, results interface{}) error {
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
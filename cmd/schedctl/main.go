@@ -0,0 +1,151 @@
+// schedctl is the administration tool for the scheduling backend. It shares the backend's
+// packages and configuration, and talks to the same MongoDB database.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+)
+
+const usage = `usage: schedctl [-o text|json] <command> [arguments]
+
+commands:
+  admin create -username NAME -email EMAIL [-name NAME] [-password PASSWORD]
+  admin promote USERNAME
+  user reset-password USERNAME [-password PASSWORD]
+  seed
+  import courses|classrooms|users FILE
+  export courses|classrooms|users [FILE]
+  generate YEAR TERM
+  approve YEAR TERM
+  migrate [up|status]
+`
+
+// errUsage is returned when a command is called with the wrong arguments
+var errUsage = errors.New("invalid arguments")
+
+// app holds what every command needs
+type app struct {
+	cfg *config.Config
+	db  *mongo.Database
+	out *output
+}
+
+// command runs a subcommand with the arguments that follow its name
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"admin":    adminCommand,
+	"user":     userCommand,
+	"seed":     seedCommand,
+	"import":   importCommand,
+	"export":   exportCommand,
+	"generate": generateCommand,
+	"approve":  approveCommand,
+	"migrate":  migrateCommand,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run parses the global flags, connects to MongoDB and runs the command, returning the exit code
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("schedctl", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("o", "text", "output format, text or json")
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() == 0 || (*format != "text" && *format != "json") {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	out := &output{json: *format == "json", w: stdout, errW: stderr}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	// Keep the backend's log lines off stdout so the output can be piped
+	logger.InitLogger(io.Discard, stderr, stderr, nil)
+
+	dir, err := os.Getwd()
+	if err != nil {
+		out.fail(err)
+		return 1
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		out.fail(fmt.Errorf("invalid configuration: %w", err))
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, cfg.MongoClientOptions())
+	if err != nil {
+		out.fail(err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		out.fail(err)
+		return 1
+	}
+
+	a := &app{cfg: cfg, db: client.Database("schedule_db"), out: out}
+	err = cmd(ctx, a, flags.Args()[1:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if err != nil {
+		out.fail(err)
+		return 1
+	}
+	return 0
+}
+
+// output prints command results either as plain text or as JSON
+type output struct {
+	json bool
+	w    io.Writer
+	errW io.Writer
+}
+
+// result prints the text for humans, or the value as JSON
+func (o *output) result(text string, value interface{}) {
+	if o.json {
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(value)
+		return
+	}
+	fmt.Fprintln(o.w, strings.TrimRight(text, "\n"))
+}
+
+// fail prints an error in the selected format
+func (o *output) fail(err error) {
+	if o.json {
+		json.NewEncoder(o.errW).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	fmt.Fprintln(o.errW, "error:", err)
+}
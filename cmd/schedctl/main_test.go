@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// testApp returns an app writing to buffers, with no database
+func testApp(json bool) (*app, *bytes.Buffer, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	return &app{cfg: config.Default(), out: &output{json: json, w: stdout, errW: stderr}}, stdout, stderr
+}
+
+// testDatabase connects to the MongoDB the integration tests use and returns a database of
+// its own, dropped when the test ends
+func testDatabase(t *testing.T, name string) *mongo.Database {
+	// The tests always run against the local database, whatever the .env file selects
+	godotenv.Load(filepath.Join("..", "..", ".env"))
+	cfg := config.Default()
+	cfg.Environment = config.Development
+	cfg.Mongo = config.MongoConfig{
+		Host:     os.Getenv("MONGO_LOCAL_HOST"),
+		Username: os.Getenv("MONGO_LOCAL_USERNAME"),
+		Password: os.Getenv("MONGO_LOCAL_PASSWORD"),
+	}
+
+	client, err := mongo.Connect(context.TODO(), cfg.MongoClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Ping(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database(name)
+	t.Cleanup(func() {
+		db.Drop(context.TODO())
+		client.Disconnect(context.TODO())
+	})
+	return db
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-o", "yaml", "seed"},
+		{"-x", "seed"},
+		{"unknown"},
+	} {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		if code := run(args, stdout, stderr); code != 2 {
+			t.Errorf("Expected exit code 2 for %q. Got %d", args, code)
+		}
+		if !strings.HasPrefix(stderr.String(), "usage: schedctl") {
+			t.Errorf("Expected the usage for %q. Got %q", args, stderr.String())
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("Expected nothing on stdout for %q. Got %q", args, stdout.String())
+		}
+	}
+}
+
+func TestCommandArguments(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	os.WriteFile(file, []byte("[]"), 0644)
+
+	// Each is refused before the database is used
+	for _, test := range []struct {
+		command string
+		args    []string
+	}{
+		{"admin", nil},
+		{"admin", []string{"demote", "alice"}},
+		{"admin", []string{"promote"}},
+		{"admin", []string{"promote", "alice", "bob"}},
+		{"admin", []string{"create", "-username", "alice"}},
+		{"admin", []string{"create", "-email", "alice@uvic.ca"}},
+		{"admin", []string{"create", "-username", "alice", "-email", "alice@uvic.ca", "-admin"}},
+		{"user", []string{"reset-password"}},
+		{"user", []string{"delete", "alice"}},
+		{"user", []string{"reset-password", "alice", "-length", "4"}},
+		{"seed", []string{"courses"}},
+		{"import", []string{"courses"}},
+		{"import", []string{"terms", file}},
+		{"export", nil},
+		{"export", []string{"terms"}},
+		{"export", []string{"courses", "a.json", "b.json"}},
+		{"generate", []string{"2024"}},
+		{"approve", []string{"2024", "fall", "spring"}},
+		{"migrate", []string{"down"}},
+	} {
+		a, stdout, _ := testApp(false)
+		err := commands[test.command](context.TODO(), a, test.args)
+		if !errors.Is(err, errUsage) {
+			t.Errorf("Expected %s %q to be refused. Got %v", test.command, test.args, err)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("Expected no output for %s %q. Got %q", test.command, test.args, stdout.String())
+		}
+	}
+
+	a, _, _ := testApp(false)
+	err := approveCommand(context.TODO(), a, []string{"next", "fall"})
+	if err == nil || err.Error() != `invalid year "next"` {
+		t.Errorf("Expected an invalid year. Got %v", err)
+	}
+	a, _, _ = testApp(false)
+	err = importCommand(context.TODO(), a, []string{"courses", filepath.Join(t.TempDir(), "missing.json")})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file. Got %v", err)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	summary := importSummary{Resource: "courses", Inserted: 2, Updated: 1}
+
+	a, stdout, _ := testApp(false)
+	a.out.result("courses: 2 inserted, 1 updated\n", summary)
+	if got := stdout.String(); got != "courses: 2 inserted, 1 updated\n" {
+		t.Errorf("Unexpected text output %q", got)
+	}
+
+	a, stdout, _ = testApp(true)
+	a.out.result("courses: 2 inserted, 1 updated", summary)
+	var decoded importSummary
+	err := json.Unmarshal(stdout.Bytes(), &decoded)
+	if err != nil || decoded != summary {
+		t.Errorf("Expected %+v as JSON. Got %q", summary, stdout.String())
+	}
+
+	a, stdout, stderr := testApp(false)
+	a.out.fail(errors.New("user alice not found"))
+	if got := stderr.String(); got != "error: user alice not found\n" || stdout.Len() != 0 {
+		t.Errorf("Unexpected text error %q", got)
+	}
+
+	a, stdout, stderr = testApp(true)
+	a.out.fail(errors.New("user alice not found"))
+	var failure map[string]string
+	err = json.Unmarshal(stderr.Bytes(), &failure)
+	if err != nil || failure["error"] != "user alice not found" || stdout.Len() != 0 {
+		t.Errorf("Unexpected JSON error %q", stderr.String())
+	}
+}
+
+// TestImportExportRoundTrip imports a file into one database, exports it and imports the export
+// into another, which must then export the same data
+func TestImportExportRoundTrip(t *testing.T) {
+	source := testDatabase(t, "schedctl_test_source")
+	copied := testDatabase(t, "schedctl_test_copy")
+
+	dir := t.TempDir()
+	files := map[string]string{
+		// The second course leaves out fields that have defaults
+		"courses": `[
+			{"shorthand": "TST100", "name": "Testing I", "prerequisites": [], "corequisites": [], "terms_offered": ["fall"],
+			 "hours": {"lecture": 3, "lab": 1, "tutorial": 0}, "sections": 2, "min_enroll": 5, "max_enroll": 100, "peng": false,
+			 "required_features": [], "room_type": ""},
+			{"shorthand": "TST200", "name": "Testing II", "prerequisites": [["TST100"]]}
+		]`,
+		"classrooms": `[{"building": "TST", "room": "101", "capacity": 80, "room_type": "lecture", "features": ["projector"], "blackouts": []}]`,
+		"users":      `[{"username": "tester", "email": "tester@uvic.ca", "password": "changeme", "name": "Tester", "max_courses": 2}]`,
+	}
+	resources := []string{"courses", "classrooms", "users"}
+
+	exports := map[string][]byte{}
+	for _, resource := range resources {
+		path := filepath.Join(dir, resource+".json")
+		os.WriteFile(path, []byte(files[resource]), 0644)
+
+		a, stdout, _ := testApp(true)
+		a.db = source
+		err := importCommand(context.TODO(), a, []string{resource, path})
+		if err != nil {
+			t.Fatalf("Expected %s to import. Got %v", resource, err)
+		}
+		var summary importSummary
+		json.Unmarshal(stdout.Bytes(), &summary)
+		var items []interface{}
+		json.Unmarshal([]byte(files[resource]), &items)
+		if summary.Inserted != int64(len(items)) || summary.Updated != 0 {
+			t.Errorf("Expected %d %s inserted. Got %+v", len(items), resource, summary)
+		}
+
+		// Without a file the export goes to the output
+		a, stdout, _ = testApp(false)
+		a.db = source
+		err = exportCommand(context.TODO(), a, []string{resource})
+		if err != nil {
+			t.Fatalf("Expected %s to export. Got %v", resource, err)
+		}
+		exports[resource] = stdout.Bytes()
+
+		exported := filepath.Join(dir, resource+"-export.json")
+		a, stdout, _ = testApp(false)
+		a.db = source
+		err = exportCommand(context.TODO(), a, []string{resource, exported})
+		if err != nil {
+			t.Fatalf("Expected %s to export to a file. Got %v", resource, err)
+		}
+		if got := stdout.String(); got != "exported "+resource+" to "+exported+"\n" {
+			t.Errorf("Unexpected export output %q", got)
+		}
+
+		a, _, _ = testApp(false)
+		a.db = copied
+		err = importCommand(context.TODO(), a, []string{resource, exported})
+		if err != nil {
+			t.Fatalf("Expected the %s export to import. Got %v", resource, err)
+		}
+	}
+
+	for _, resource := range resources {
+		a, stdout, _ := testApp(false)
+		a.db = copied
+		err := exportCommand(context.TODO(), a, []string{resource})
+		if err != nil {
+			t.Fatalf("Expected %s to export. Got %v", resource, err)
+		}
+		if !bytes.Equal(stdout.Bytes(), exports[resource]) {
+			t.Errorf("Expected the %s to survive the round trip.\nBefore: %s\nAfter: %s", resource, exports[resource], stdout.Bytes())
+		}
+	}
+
+	// Defaults are filled in, passwords are hashed on import and never exported
+	var exported []courses.Course
+	json.Unmarshal(exports["courses"], &exported)
+	if len(exported) != 2 || exported[1].Sections != 1 || exported[1].MinEnroll != 5 || exported[1].Hours.Lecture != 3 {
+		t.Errorf("Expected defaults for the fields a course left out. Got %s", exports["courses"])
+	}
+	if strings.Contains(string(exports["users"]), "changeme") {
+		t.Errorf("Expected no password in the export. Got %s", exports["users"])
+	}
+	var user users.User
+	source.Collection("users").FindOne(context.TODO(), bson.M{"username": "tester"}).Decode(&user)
+	if !strings.HasPrefix(user.Password, "$2") {
+		t.Errorf("Expected the password to be hashed. Got %q", user.Password)
+	}
+
+	// Importing again updates every document and moves it to the next version
+	a, stdout, _ := testApp(true)
+	a.db = source
+	err := importCommand(context.TODO(), a, []string{"classrooms", filepath.Join(dir, "classrooms.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary importSummary
+	json.Unmarshal(stdout.Bytes(), &summary)
+	if summary.Inserted != 0 || summary.Updated != 1 {
+		t.Errorf("Expected the classroom to be updated. Got %+v", summary)
+	}
+}
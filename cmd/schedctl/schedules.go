@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SENG-499-Company2-B01/Backend/migrations"
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+)
+
+// generateCommand generates a draft schedule for the year and term
+func generateCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+
+	schedule, err := schedules.Generate(ctx, args[0], args[1],
+		a.db.Collection("draft_schedules"), a.db.Collection("users"), a.db.Collection("courses"),
+		a.db.Collection("classrooms"), a.db.Collection("terms"), a.db.Collection("blocks"), a.cfg.Algs1API, a.cfg.Algs2API)
+	if err != nil {
+		return err
+	}
+
+	sections := 0
+	offerings := 0
+	for _, term := range schedule.Terms {
+		offerings += len(term.Courses)
+		for _, offering := range term.Courses {
+			sections += len(offering.Sections)
+		}
+	}
+	a.out.result(fmt.Sprintf("generated draft schedule for %s %s: %d courses, %d sections", args[1], args[0], offerings, sections), schedule)
+	return nil
+}
+
+// approveCommand moves the draft schedule for the year and term to the previous schedules
+func approveCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	year, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid year %q", args[0])
+	}
+
+	err = schedules.Approve(ctx, year, args[1], a.db.Collection("draft_schedules"), a.db.Collection("previous_schedules"), a.db.Collection("terms"), a.db.Collection("blocks"),
+		a.db.Collection("courses"), a.db.Collection("classrooms"))
+	if err != nil {
+		return err
+	}
+
+	a.out.result(fmt.Sprintf("approved schedule for %s %d", args[1], year), map[string]interface{}{"year": year, "term": args[1], "approved": true})
+	return nil
+}
+
+// migrateCommand applies pending migrations, or lists their status with "migrate status"
+func migrateCommand(ctx context.Context, a *app, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		ran, err := migrations.Run(ctx, a.db)
+		if err != nil {
+			return err
+		}
+		text := "database is up to date"
+		if len(ran) > 0 {
+			text = ""
+			for _, migration := range ran {
+				text += fmt.Sprintf("applied %d: %s\n", migration.Version, migration.Description)
+			}
+		}
+		a.out.result(text, ran)
+	case "status":
+		statuses, err := migrations.Status(ctx, a.db)
+		if err != nil {
+			return err
+		}
+		text := ""
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			text += fmt.Sprintf("%d\t%s\t%s\n", status.Version, status.Description, applied)
+		}
+		a.out.result(text, statuses)
+	default:
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// adminCommand creates a new admin or promotes an existing user
+func adminCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "create":
+		return createAdmin(ctx, a, args[1:])
+	case "promote":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return promoteAdmin(ctx, a, args[1])
+	default:
+		return errUsage
+	}
+}
+
+// createAdmin inserts a new user with admin rights
+func createAdmin(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	username := flags.String("username", "", "username of the new admin")
+	email := flags.String("email", "", "email of the new admin")
+	name := flags.String("name", "", "display name of the new admin")
+	password := flags.String("password", "", "password, generated if empty")
+	err := flags.Parse(args)
+	if err != nil || *username == "" || *email == "" {
+		return errUsage
+	}
+
+	generated := *password == ""
+	if generated {
+		*password, err = randomPassword()
+		if err != nil {
+			return err
+		}
+	}
+	hash, err := users.HashPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	admin := users.User{
+		Username: *username,
+		Email:    *email,
+		Name:     *name,
+		Password: hash,
+		IsAdmin:  true,
+		Version:  1,
+	}
+
+	collection := a.db.Collection("users")
+	count, err := collection.CountDocuments(ctx, bson.M{"$or": []bson.M{{"username": admin.Username}, {"email": admin.Email}}})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("username or email already exists")
+	}
+	_, err = collection.InsertOne(ctx, admin)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("username or email already exists")
+	}
+	if err != nil {
+		return err
+	}
+	err = users.RecordRoleChange(ctx, a.db.Collection("role_changes"), admin.Username, true, "schedctl")
+	if err != nil {
+		return err
+	}
+
+	result := map[string]interface{}{"username": admin.Username, "email": admin.Email, "isAdmin": true}
+	text := fmt.Sprintf("created admin %s", admin.Username)
+	if generated {
+		result["password"] = *password
+		text += fmt.Sprintf(" with password %s", *password)
+	}
+	a.out.result(text, result)
+	return nil
+}
+
+// promoteAdmin gives an existing user admin rights
+func promoteAdmin(ctx context.Context, a *app, username string) error {
+	res, err := a.db.Collection("users").UpdateOne(ctx, bson.M{"username": username}, bson.M{"$set": bson.M{"isAdmin": true}, "$inc": bson.M{etag.Field: 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("user %s not found", username)
+	}
+	err = users.RecordRoleChange(ctx, a.db.Collection("role_changes"), username, true, "schedctl")
+	if err != nil {
+		return err
+	}
+
+	a.out.result(fmt.Sprintf("promoted %s to admin", username), map[string]interface{}{"username": username, "isAdmin": true})
+	return nil
+}
+
+// userCommand manages existing users
+func userCommand(ctx context.Context, a *app, args []string) error {
+	if len(args) < 2 || args[0] != "reset-password" {
+		return errUsage
+	}
+	return resetPassword(ctx, a, args[1], args[2:])
+}
+
+// resetPassword sets a new password for the user, generating one if none is given
+func resetPassword(ctx context.Context, a *app, username string, args []string) error {
+	flags := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	password := flags.String("password", "", "new password, generated if empty")
+	err := flags.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+
+	generated := *password == ""
+	if generated {
+		*password, err = randomPassword()
+		if err != nil {
+			return err
+		}
+	}
+	hash, err := users.HashPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.db.Collection("users").UpdateOne(ctx, bson.M{"username": username}, bson.M{"$set": bson.M{"password": hash}, "$inc": bson.M{etag.Field: 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("user %s not found", username)
+	}
+
+	result := map[string]interface{}{"username": username}
+	text := fmt.Sprintf("reset password for %s", username)
+	if generated {
+		result["password"] = *password
+		text += fmt.Sprintf(", new password is %s", *password)
+	}
+	a.out.result(text, result)
+	return nil
+}
+
+// randomPassword returns a random password suitable for handing to a user once
+func randomPassword() (string, error) {
+	buf := make([]byte, 12)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
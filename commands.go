@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SENG-499-Company2-B01/Backend/migrations"
+)
+
+// runCommand runs a command line subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "migrate":
+		return migrateCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\nusage: app [migrate [up|status]]\n", args[0])
+		return 2
+	}
+}
+
+// migrateCommand applies pending migrations, or lists their status with "migrate status"
+func migrateCommand(args []string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("schedule_db")
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		ran, err := migrations.Run(ctx, db)
+		for _, migration := range ran {
+			fmt.Printf("applied %d: %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(ran) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "status":
+		statuses, err := migrations.Status(ctx, db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d\t%s\t%s\n", status.Version, status.Description, applied)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action %q, expected up or status\n", action)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,684 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// Config holds every setting the backend needs at runtime
+type Config struct {
+	Environment string      `yaml:"environment" toml:"environment"`
+	Mongo       MongoConfig `yaml:"mongo" toml:"mongo"`
+	Algs1API    string      `yaml:"algs1_api" toml:"algs1_api"`
+	Algs2API    string      `yaml:"algs2_api" toml:"algs2_api"`
+	JWTSecret   string      `yaml:"jwt_secret" toml:"jwt_secret"`
+	APIHash     string      `yaml:"api_hash" toml:"api_hash"`
+	// APIKeys maps the name of each API key to the SHA-256 hash of the key, the name is what the
+	// audit log records as the actor
+	APIKeys   map[string]string `yaml:"api_keys" toml:"api_keys"`
+	Server    ServerConfig      `yaml:"server" toml:"server"`
+	Login     LoginConfig       `yaml:"login" toml:"login"`
+	RateLimit RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	CORS      CORSConfig        `yaml:"cors" toml:"cors"`
+	Mail      MailConfig        `yaml:"mail" toml:"mail"`
+	// PasswordReset configures the links emailed to users who forgot their password
+	PasswordReset PasswordResetConfig `yaml:"password_reset" toml:"password_reset"`
+	Notifications NotificationsConfig `yaml:"notifications" toml:"notifications"`
+
+	// MigrateOnStartup applies pending database migrations before serving requests
+	MigrateOnStartup bool `yaml:"migrate_on_startup" toml:"migrate_on_startup"`
+
+	// ValidateRequests rejects request bodies that don't match the OpenAPI document
+	ValidateRequests bool `yaml:"validate_requests" toml:"validate_requests"`
+
+	// Admins are the usernames made admins at startup while the database has no active admin
+	Admins []string `yaml:"admins" toml:"admins"`
+}
+
+// MongoConfig holds the connection settings for the environment in use
+type MongoConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// ServerConfig holds the settings used to build the HTTP server
+type ServerConfig struct {
+	Address           string   `yaml:"address" toml:"address"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout   Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	MaxHeaderBytes    int      `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	MaxBodyBytes      int64    `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	TLSCertFile       string   `yaml:"tls_cert_file" toml:"tls_cert_file"`
+	TLSKeyFile        string   `yaml:"tls_key_file" toml:"tls_key_file"`
+	// TrustProxyHeaders takes the client address from X-Forwarded-For, only enable it behind a
+	// proxy that sets the header
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" toml:"trust_proxy_headers"`
+}
+
+// LoginConfig holds the limits on failed logins. After each failure a username or address must
+// wait twice as long as after the previous one before trying again, starting at Backoff, until it
+// reaches its maximum failures and is locked out for Lockout.
+type LoginConfig struct {
+	MaxFailures   int      `yaml:"max_failures" toml:"max_failures"`
+	IPMaxFailures int      `yaml:"ip_max_failures" toml:"ip_max_failures"`
+	Backoff       Duration `yaml:"backoff" toml:"backoff"`
+	Lockout       Duration `yaml:"lockout" toml:"lockout"`
+	// FailureWindow is how long a failure counts towards the maximum
+	FailureWindow Duration `yaml:"failure_window" toml:"failure_window"`
+}
+
+// RateLimitConfig holds the token buckets limiting how often a client may call the API. Every API
+// key, user and address has its own buckets, and logging in and generating schedules take from a
+// stricter bucket of their own as well as the general one.
+type RateLimitConfig struct {
+	Enabled  bool      `yaml:"enabled" toml:"enabled"`
+	General  RateLimit `yaml:"general" toml:"general"`
+	Login    RateLimit `yaml:"login" toml:"login"`
+	Generate RateLimit `yaml:"generate" toml:"generate"`
+}
+
+// RateLimit is a token bucket holding up to Burst requests, refilled with Requests every Period
+type RateLimit struct {
+	Requests int      `yaml:"requests" toml:"requests"`
+	Period   Duration `yaml:"period" toml:"period"`
+	Burst    int      `yaml:"burst" toml:"burst"`
+}
+
+// CORSConfig holds the cross-origin policy sent to browsers. Settings left out get the defaults
+// of the environment, see DefaultCORS.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers" toml:"allowed_headers"`
+	// AllowCredentials lets browsers send cookies and Authorization headers they manage themselves
+	AllowCredentials bool `yaml:"allow_credentials" toml:"allow_credentials"`
+	// MaxAge is how long browsers may cache the answer to a preflight request, 0 for the default.
+	// Browsers cap it themselves, and it can't be more than 10 minutes.
+	MaxAge Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// DefaultCORS returns the policy of the environment. Development allows any origin so the
+// frontend can run anywhere, production allows none until CORS_ALLOWED_ORIGINS lists them.
+func DefaultCORS(environment string) CORSConfig {
+	cors := CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization", "apikey", "If-Match", "If-None-Match", "X-Request-ID"},
+		MaxAge:         Duration{time.Minute},
+	}
+	if environment == Production {
+		cors.AllowedOrigins = []string{}
+		cors.MaxAge = Duration{10 * time.Minute}
+	}
+	return cors
+}
+
+// Mail drivers, the ways email can be sent
+const (
+	MailSMTP = "smtp"
+	MailFile = "file"
+	MailLog  = "log"
+)
+
+// MailConfig holds how email is sent. The file and log drivers keep messages on the server for
+// development, the driver defaults to log in development and smtp in production.
+type MailConfig struct {
+	Driver string     `yaml:"driver" toml:"driver"`
+	From   string     `yaml:"from" toml:"from"`
+	Dir    string     `yaml:"dir" toml:"dir"`
+	SMTP   SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+// SMTPConfig holds the server the smtp mail driver sends through
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// PasswordResetConfig holds the links sent to reset a password. URL is the page of the frontend
+// that asks for the new password, the token is added to its query. Without a URL password resets
+// are turned off.
+type PasswordResetConfig struct {
+	URL string   `yaml:"url" toml:"url"`
+	TTL Duration `yaml:"ttl" toml:"ttl"`
+}
+
+// Enabled reports whether users can reset a forgotten password
+func (reset PasswordResetConfig) Enabled() bool {
+	return strings.TrimSpace(reset.URL) != ""
+}
+
+// NotificationsConfig holds how notifications are delivered. The webhook channel is only used
+// when WebhookURL is set, its requests are signed with WebhookSecret. A failed delivery is tried
+// again after RetryBackoff, doubling each time, until it has been tried MaxAttempts times.
+type NotificationsConfig struct {
+	WebhookURL    string   `yaml:"webhook_url" toml:"webhook_url"`
+	WebhookSecret string   `yaml:"webhook_secret" toml:"webhook_secret"`
+	MaxAttempts   int      `yaml:"max_attempts" toml:"max_attempts"`
+	RetryBackoff  Duration `yaml:"retry_backoff" toml:"retry_backoff"`
+	// PollInterval is how often the outbox is checked for deliveries that are due
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval"`
+}
+
+// Duration is a time.Duration that can be read from text such as "30s" or "2m"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string for the YAML and TOML decoders
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// Default returns a configuration with every optional setting filled in
+func Default() *Config {
+	return &Config{
+		Environment: Development,
+		Server: ServerConfig{
+			Address:           ":8000",
+			ReadTimeout:       Duration{15 * time.Second},
+			ReadHeaderTimeout: Duration{5 * time.Second},
+			// Generating a schedule waits on both algorithm services, so writes get a generous timeout
+			WriteTimeout:    Duration{2 * time.Minute},
+			IdleTimeout:     Duration{60 * time.Second},
+			ShutdownTimeout: Duration{30 * time.Second},
+			MaxHeaderBytes:  1 << 20,
+			MaxBodyBytes:    4 << 20,
+		},
+		Login: LoginConfig{
+			MaxFailures:   5,
+			IPMaxFailures: 50,
+			Backoff:       Duration{time.Second},
+			Lockout:       Duration{15 * time.Minute},
+			FailureWindow: Duration{time.Hour},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  true,
+			General:  RateLimit{Requests: 300, Period: Duration{time.Minute}, Burst: 60},
+			Login:    RateLimit{Requests: 10, Period: Duration{time.Minute}, Burst: 5},
+			Generate: RateLimit{Requests: 5, Period: Duration{time.Hour}, Burst: 2},
+		},
+		Mail: MailConfig{
+			From: "no-reply@localhost",
+			Dir:  "mail",
+			SMTP: SMTPConfig{Port: 587},
+		},
+		PasswordReset: PasswordResetConfig{TTL: Duration{time.Hour}},
+		Notifications: NotificationsConfig{
+			MaxAttempts:  5,
+			RetryBackoff: Duration{30 * time.Second},
+			PollInterval: Duration{5 * time.Second},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, an optional YAML or TOML file named by
+// CONFIG_FILE, an optional .env file and the process environment, in increasing order of
+// precedence. Every problem found is reported together in the returned error.
+func Load(dir string) (*Config, error) {
+	// A missing .env file is fine, the variables may already be set in the container
+	envPath := os.Getenv("ENV_FILE")
+	if envPath == "" {
+		envPath = filepath.Join(dir, ".env")
+	}
+	err := godotenv.Load(envPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error loading %s: %w", envPath, err)
+	}
+
+	config := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		err = config.loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []error
+	problems = append(problems, config.loadEnv()...)
+	// The environment is only known now, so its defaults are filled in last
+	config.fillEnvironmentDefaults()
+	problems = append(problems, config.Validate())
+
+	err = joinProblems(problems)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// joinProblems returns the problems as one error, a line each, or nil when there are none
+func joinProblems(problems []error) error {
+	var lines []string
+	for _, problem := range problems {
+		if problem != nil {
+			lines = append(lines, problem.Error())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// loadFile reads the YAML or TOML file at path into the configuration
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file type %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the configuration with any environment variables that are set
+func (c *Config) loadEnv() []error {
+	var problems []error
+
+	setString(&c.Environment, "ENVIRONMENT")
+	c.Environment = strings.ToLower(strings.TrimSpace(c.Environment))
+
+	// The Mongo variables are prefixed by the environment they belong to
+	prefix := "MONGO_LOCAL_"
+	if c.Environment == Production {
+		prefix = "MONGO_PRODUCTION_"
+	}
+	setString(&c.Mongo.Host, prefix+"HOST")
+	setString(&c.Mongo.Username, prefix+"USERNAME")
+	setString(&c.Mongo.Password, prefix+"PASSWORD")
+
+	setString(&c.Algs1API, "ALGS1_API")
+	setString(&c.Algs2API, "ALGS2_API")
+	setString(&c.JWTSecret, "JWT_SECRET")
+	setString(&c.APIHash, "API_HASH")
+	problems = appendError(problems, setMap(&c.APIKeys, "API_KEYS"))
+	problems = appendError(problems, setBool(&c.MigrateOnStartup, "MIGRATE_ON_STARTUP"))
+	problems = appendError(problems, setBool(&c.ValidateRequests, "VALIDATE_REQUESTS"))
+	setList(&c.Admins, "ADMINS")
+	// ADMIN_1 and ADMIN_2 are what the seed script reads, they are added to the list
+	for _, key := range []string{"ADMIN_1", "ADMIN_2"} {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			c.Admins = append(c.Admins, value)
+		}
+	}
+
+	setString(&c.Server.Address, "SERVER_ADDRESS")
+	problems = appendError(problems, setDuration(&c.Server.ReadTimeout, "SERVER_READ_TIMEOUT"))
+	problems = appendError(problems, setDuration(&c.Server.ReadHeaderTimeout, "SERVER_READ_HEADER_TIMEOUT"))
+	problems = appendError(problems, setDuration(&c.Server.WriteTimeout, "SERVER_WRITE_TIMEOUT"))
+	problems = appendError(problems, setDuration(&c.Server.IdleTimeout, "SERVER_IDLE_TIMEOUT"))
+	problems = appendError(problems, setDuration(&c.Server.ShutdownTimeout, "SERVER_SHUTDOWN_TIMEOUT"))
+	problems = appendError(problems, setInt(&c.Server.MaxHeaderBytes, "SERVER_MAX_HEADER_BYTES"))
+	problems = appendError(problems, setInt64(&c.Server.MaxBodyBytes, "SERVER_MAX_BODY_BYTES"))
+	setString(&c.Server.TLSCertFile, "TLS_CERT_FILE")
+	setString(&c.Server.TLSKeyFile, "TLS_KEY_FILE")
+	problems = appendError(problems, setBool(&c.Server.TrustProxyHeaders, "TRUST_PROXY_HEADERS"))
+
+	problems = appendError(problems, setInt(&c.Login.MaxFailures, "LOGIN_MAX_FAILURES"))
+	problems = appendError(problems, setInt(&c.Login.IPMaxFailures, "LOGIN_IP_MAX_FAILURES"))
+	problems = appendError(problems, setDuration(&c.Login.Backoff, "LOGIN_BACKOFF"))
+	problems = appendError(problems, setDuration(&c.Login.Lockout, "LOGIN_LOCKOUT"))
+	problems = appendError(problems, setDuration(&c.Login.FailureWindow, "LOGIN_FAILURE_WINDOW"))
+
+	problems = appendError(problems, setBool(&c.RateLimit.Enabled, "RATE_LIMIT_ENABLED"))
+	problems = append(problems, setRateLimit(&c.RateLimit.General, "RATE_LIMIT")...)
+	problems = append(problems, setRateLimit(&c.RateLimit.Login, "RATE_LIMIT_LOGIN")...)
+	problems = append(problems, setRateLimit(&c.RateLimit.Generate, "RATE_LIMIT_GENERATE")...)
+
+	setList(&c.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	setList(&c.CORS.AllowedMethods, "CORS_ALLOWED_METHODS")
+	setList(&c.CORS.AllowedHeaders, "CORS_ALLOWED_HEADERS")
+	problems = appendError(problems, setBool(&c.CORS.AllowCredentials, "CORS_ALLOW_CREDENTIALS"))
+	problems = appendError(problems, setDuration(&c.CORS.MaxAge, "CORS_MAX_AGE"))
+
+	setString(&c.Mail.Driver, "MAIL_DRIVER")
+	c.Mail.Driver = strings.ToLower(strings.TrimSpace(c.Mail.Driver))
+	setString(&c.Mail.From, "MAIL_FROM")
+	setString(&c.Mail.Dir, "MAIL_DIR")
+	setString(&c.Mail.SMTP.Host, "SMTP_HOST")
+	problems = appendError(problems, setInt(&c.Mail.SMTP.Port, "SMTP_PORT"))
+	setString(&c.Mail.SMTP.Username, "SMTP_USERNAME")
+	setString(&c.Mail.SMTP.Password, "SMTP_PASSWORD")
+	setString(&c.PasswordReset.URL, "PASSWORD_RESET_URL")
+	problems = appendError(problems, setDuration(&c.PasswordReset.TTL, "PASSWORD_RESET_TTL"))
+
+	setString(&c.Notifications.WebhookURL, "NOTIFY_WEBHOOK_URL")
+	setString(&c.Notifications.WebhookSecret, "NOTIFY_WEBHOOK_SECRET")
+	problems = appendError(problems, setInt(&c.Notifications.MaxAttempts, "NOTIFY_MAX_ATTEMPTS"))
+	problems = appendError(problems, setDuration(&c.Notifications.RetryBackoff, "NOTIFY_RETRY_BACKOFF"))
+	problems = appendError(problems, setDuration(&c.Notifications.PollInterval, "NOTIFY_POLL_INTERVAL"))
+
+	return problems
+}
+
+// fillEnvironmentDefaults sets the settings left out whose default depends on the environment
+func (c *Config) fillEnvironmentDefaults() {
+	c.CORS.fill(c.Environment)
+	// Production sends email once an SMTP server is given, until then it is only logged
+	if c.Mail.Driver == "" {
+		c.Mail.Driver = MailLog
+		if c.Environment == Production && c.Mail.SMTP.Host != "" {
+			c.Mail.Driver = MailSMTP
+		}
+	}
+	// The frontend's development server
+	if c.PasswordReset.URL == "" && c.Environment == Development {
+		c.PasswordReset.URL = "http://localhost:3000/reset-password"
+	}
+}
+
+// fill sets every CORS setting left out to the default of the environment
+func (cors *CORSConfig) fill(environment string) {
+	defaults := DefaultCORS(environment)
+	if cors.AllowedOrigins == nil {
+		cors.AllowedOrigins = defaults.AllowedOrigins
+	}
+	if cors.AllowedMethods == nil {
+		cors.AllowedMethods = defaults.AllowedMethods
+	}
+	if cors.AllowedHeaders == nil {
+		cors.AllowedHeaders = defaults.AllowedHeaders
+	}
+	if cors.MaxAge.Duration == 0 {
+		cors.MaxAge = defaults.MaxAge
+	}
+}
+
+// Validate checks that every setting required by the environment is present and sane
+func (c *Config) Validate() error {
+	var problems []error
+	require := func(value string, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, fmt.Errorf("%s is required in %s", name, c.Environment))
+		}
+	}
+
+	switch c.Environment {
+	case Development:
+		require(c.Mongo.Host, "MONGO_LOCAL_HOST")
+		require(c.Mongo.Username, "MONGO_LOCAL_USERNAME")
+		require(c.Mongo.Password, "MONGO_LOCAL_PASSWORD")
+	case Production:
+		require(c.Mongo.Host, "MONGO_PRODUCTION_HOST")
+		require(c.Mongo.Username, "MONGO_PRODUCTION_USERNAME")
+		require(c.Mongo.Password, "MONGO_PRODUCTION_PASSWORD")
+	default:
+		problems = append(problems, fmt.Errorf("ENVIRONMENT must be %q or %q, got %q", Development, Production, c.Environment))
+	}
+
+	require(c.Algs1API, "ALGS1_API")
+	require(c.Algs2API, "ALGS2_API")
+	require(c.JWTSecret, "JWT_SECRET")
+	if c.APIHash == "" && len(c.APIKeys) == 0 {
+		problems = append(problems, fmt.Errorf("API_HASH or API_KEYS is required in %s", c.Environment))
+	}
+	if _, ok := c.APIKeys[DefaultAPIKey]; ok && c.APIHash != "" {
+		problems = append(problems, fmt.Errorf("API_KEYS can't name a key %q while API_HASH is set", DefaultAPIKey))
+	}
+
+	if c.Server.Address == "" {
+		problems = append(problems, errors.New("SERVER_ADDRESS must not be empty"))
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		problems = append(problems, errors.New("SERVER_MAX_HEADER_BYTES must be positive"))
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		problems = append(problems, errors.New("SERVER_MAX_BODY_BYTES must be positive"))
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		problems = append(problems, errors.New("SERVER_SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.Login.MaxFailures <= 0 || c.Login.IPMaxFailures <= 0 {
+		problems = append(problems, errors.New("LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must be positive"))
+	}
+	if c.Login.Backoff.Duration < 0 || c.Login.Lockout.Duration <= 0 || c.Login.FailureWindow.Duration <= 0 {
+		problems = append(problems, errors.New("LOGIN_LOCKOUT and LOGIN_FAILURE_WINDOW must be positive and LOGIN_BACKOFF can't be negative"))
+	}
+	if c.RateLimit.Enabled {
+		limits := []RateLimit{c.RateLimit.General, c.RateLimit.Login, c.RateLimit.Generate}
+		for i, name := range []string{"RATE_LIMIT", "RATE_LIMIT_LOGIN", "RATE_LIMIT_GENERATE"} {
+			if limits[i].Requests <= 0 || limits[i].Period.Duration <= 0 || limits[i].Burst <= 0 {
+				problems = append(problems, fmt.Errorf("%s_REQUESTS, %s_PERIOD and %s_BURST must be positive", name, name, name))
+			}
+		}
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" && c.Environment == Production {
+			problems = append(problems, errors.New("CORS_ALLOWED_ORIGINS can't be * in production, list the frontend's origins"))
+		}
+		if origin == "*" && c.CORS.AllowCredentials {
+			problems = append(problems, errors.New("CORS_ALLOW_CREDENTIALS needs CORS_ALLOWED_ORIGINS to list origins rather than *"))
+		}
+	}
+	if c.CORS.MaxAge.Duration < 0 || c.CORS.MaxAge.Duration > 10*time.Minute {
+		problems = append(problems, errors.New("CORS_MAX_AGE must be between 0 and 10m"))
+	}
+	switch c.Mail.Driver {
+	case MailSMTP:
+		require(c.Mail.SMTP.Host, "SMTP_HOST")
+		if c.Mail.SMTP.Port <= 0 {
+			problems = append(problems, errors.New("SMTP_PORT must be positive"))
+		}
+	case MailFile:
+		require(c.Mail.Dir, "MAIL_DIR")
+	case MailLog:
+	default:
+		problems = append(problems, fmt.Errorf("MAIL_DRIVER must be %q, %q or %q, got %q", MailSMTP, MailFile, MailLog, c.Mail.Driver))
+	}
+	require(c.Mail.From, "MAIL_FROM")
+	if c.PasswordReset.Enabled() {
+		link, err := url.Parse(c.PasswordReset.URL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			problems = append(problems, errors.New("PASSWORD_RESET_URL must be an http or https URL"))
+		}
+	}
+	if c.PasswordReset.TTL.Duration <= 0 {
+		problems = append(problems, errors.New("PASSWORD_RESET_TTL must be positive"))
+	}
+	if c.Notifications.WebhookURL != "" {
+		webhook, err := url.Parse(c.Notifications.WebhookURL)
+		if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			problems = append(problems, errors.New("NOTIFY_WEBHOOK_URL must be an http or https URL"))
+		}
+		require(c.Notifications.WebhookSecret, "NOTIFY_WEBHOOK_SECRET")
+	}
+	if c.Notifications.MaxAttempts <= 0 {
+		problems = append(problems, errors.New("NOTIFY_MAX_ATTEMPTS must be positive"))
+	}
+	if c.Notifications.RetryBackoff.Duration <= 0 {
+		problems = append(problems, errors.New("NOTIFY_RETRY_BACKOFF must be positive"))
+	}
+	if c.Notifications.PollInterval.Duration <= 0 {
+		problems = append(problems, errors.New("NOTIFY_POLL_INTERVAL must be positive"))
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		problems = append(problems, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+
+	return joinProblems(problems)
+}
+
+// DefaultAPIKey is the name of the key whose hash is API_HASH
+const DefaultAPIKey = "default"
+
+// APIKeyHashes returns the hash of every API key by name, including API_HASH
+func (c *Config) APIKeyHashes() map[string]string {
+	hashes := map[string]string{}
+	for name, hash := range c.APIKeys {
+		hashes[name] = hash
+	}
+	if c.APIHash != "" {
+		hashes[DefaultAPIKey] = c.APIHash
+	}
+	return hashes
+}
+
+// MongoClientOptions returns the client options for connecting to the configured database
+func (c *Config) MongoClientOptions() *options.ClientOptions {
+	if c.Environment == Production {
+		// Use the MongoDB Atlas connection string
+		connectionString := fmt.Sprintf("mongodb+srv://%s:%s@%s/?retryWrites=true&w=majority", c.Mongo.Username, c.Mongo.Password, c.Mongo.Host)
+		return options.Client().ApplyURI(connectionString)
+	}
+
+	// Set up the local MongoDB client with SCRAM-SHA-256 authentication
+	return options.Client().ApplyURI("mongodb://" + c.Mongo.Host).
+		SetAuth(options.Credential{
+			Username:      c.Mongo.Username,
+			Password:      c.Mongo.Password,
+			AuthMechanism: "SCRAM-SHA-256",
+		})
+}
+
+// setString copies the environment variable into target when it is set
+func setString(target *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = strings.TrimSpace(value)
+	}
+}
+
+// setList splits the comma separated environment variable into target when it is set
+func setList(target *[]string, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	*target = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*target = append(*target, item)
+		}
+	}
+}
+
+// setMap parses the environment variable, a comma separated list of name=value pairs, into
+// target when it is set
+func setMap(target *map[string]string, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		name, entry, found := strings.Cut(item, "=")
+		name, entry = strings.TrimSpace(name), strings.TrimSpace(entry)
+		if !found || name == "" || entry == "" {
+			return fmt.Errorf("%s must be a comma separated list of name=value pairs: %q", key, item)
+		}
+		parsed[name] = entry
+	}
+	*target = parsed
+	return nil
+}
+
+// setBool parses the environment variable into target when it is set
+func setBool(target *bool, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid boolean: %q", key, value)
+	}
+	*target = parsed
+	return nil
+}
+
+// setDuration parses the environment variable into target when it is set
+func setDuration(target *Duration, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid duration: %q", key, value)
+	}
+	target.Duration = duration
+	return nil
+}
+
+// setInt parses the environment variable into target when it is set
+func setInt(target *int, key string) error {
+	number := int64(*target)
+	err := setInt64(&number, key)
+	if err != nil {
+		return err
+	}
+	*target = int(number)
+	return nil
+}
+
+// setInt64 parses the environment variable into target when it is set
+func setInt64(target *int64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil
+	}
+	number, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid integer: %q", key, value)
+	}
+	*target = number
+	return nil
+}
+
+// setRateLimit overrides the bucket with the _REQUESTS, _PERIOD and _BURST variables of the prefix
+func setRateLimit(target *RateLimit, prefix string) []error {
+	var problems []error
+	problems = appendError(problems, setInt(&target.Requests, prefix+"_REQUESTS"))
+	problems = appendError(problems, setDuration(&target.Period, prefix+"_PERIOD"))
+	problems = appendError(problems, setInt(&target.Burst, prefix+"_BURST"))
+	return problems
+}
+
+// appendError appends err to problems if it is not nil
+func appendError(problems []error, err error) []error {
+	if err != nil {
+		return append(problems, err)
+	}
+	return problems
+}
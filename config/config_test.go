@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadedKeys are the variables the tests set, directly or through a .env file
+var loadedKeys = []string{
+	"ENV_FILE", "CONFIG_FILE", "ENVIRONMENT",
+	"MONGO_LOCAL_HOST", "MONGO_LOCAL_USERNAME", "MONGO_LOCAL_PASSWORD",
+	"MONGO_PRODUCTION_HOST", "MONGO_PRODUCTION_USERNAME", "MONGO_PRODUCTION_PASSWORD",
+	"ALGS1_API", "ALGS2_API", "JWT_SECRET", "API_HASH", "SERVER_ADDRESS", "SERVER_SHUTDOWN_TIMEOUT",
+	"CORS_ALLOWED_ORIGINS", "PASSWORD_RESET_URL",
+}
+
+// clearEnv unsets the loaded variables for the test and restores them after it. godotenv never
+// overrides a variable that is set, even to "", so they must be unset for the .env file to apply.
+func clearEnv(t *testing.T) {
+	for _, key := range loadedKeys {
+		value, ok := os.LookupEnv(key)
+		os.Unsetenv(key)
+		key := key
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, value)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+// writeFile writes the content to a file named name in dir and returns its path
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// required are the settings development can't start without
+const required = `ENVIRONMENT=development
+MONGO_LOCAL_HOST=localhost:27017
+MONGO_LOCAL_USERNAME=admin
+MONGO_LOCAL_PASSWORD=secret
+ALGS2_API=http://localhost:9002/predict
+API_HASH=abc
+`
+
+func TestLoadPrecedence(t *testing.T) {
+	for _, file := range []struct{ name, content string }{
+		{"config.yaml", "algs1_api: http://file/schedule\njwt_secret: file\nserver:\n  address: \":7000\"\n  shutdown_timeout: 45s\n"},
+		{"config.toml", "algs1_api = \"http://file/schedule\"\njwt_secret = \"file\"\n[server]\naddress = \":7000\"\nshutdown_timeout = \"45s\"\n"},
+	} {
+		t.Run(file.name, func(t *testing.T) {
+			clearEnv(t)
+			dir := t.TempDir()
+			writeFile(t, dir, ".env", required+"ALGS1_API=http://dotenv/schedule\nJWT_SECRET=dotenv\n")
+			os.Setenv("CONFIG_FILE", writeFile(t, dir, file.name, file.content))
+			os.Setenv("JWT_SECRET", "env")
+
+			cfg, err := Load(dir)
+			if err != nil {
+				t.Fatalf("Expected the configuration to load. Got %v", err)
+			}
+			// The environment beats .env, which beats the file, which beats the defaults
+			if cfg.JWTSecret != "env" {
+				t.Errorf("Expected JWT_SECRET from the environment. Got %q", cfg.JWTSecret)
+			}
+			if cfg.Algs1API != "http://dotenv/schedule" {
+				t.Errorf("Expected ALGS1_API from .env. Got %q", cfg.Algs1API)
+			}
+			if cfg.Server.Address != ":7000" || cfg.Server.ShutdownTimeout.Duration != 45*time.Second {
+				t.Errorf("Expected the server settings from the file. Got %+v", cfg.Server)
+			}
+			if cfg.Server.MaxBodyBytes != Default().Server.MaxBodyBytes {
+				t.Errorf("Expected the default body limit. Got %d", cfg.Server.MaxBodyBytes)
+			}
+			// Development fills in its own defaults
+			if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "*" || !cfg.PasswordReset.Enabled() {
+				t.Errorf("Expected the development defaults. Got %+v, %+v", cfg.CORS, cfg.PasswordReset)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", required)
+	os.Setenv("SERVER_SHUTDOWN_TIMEOUT", "soon")
+
+	_, err := Load(dir)
+	if err == nil {
+		t.Fatal("Expected the configuration to be refused")
+	}
+	for _, problem := range []string{"SERVER_SHUTDOWN_TIMEOUT is not a valid duration", "ALGS1_API is required", "JWT_SECRET is required"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected %q to be reported. Got %v", problem, err)
+		}
+	}
+
+	os.Setenv("CONFIG_FILE", writeFile(t, dir, "config.json", "{}"))
+	_, err = Load(dir)
+	if err == nil || !strings.Contains(err.Error(), "unsupported config file type") {
+		t.Errorf("Expected a JSON file to be refused. Got %v", err)
+	}
+}
+
+// valid returns a development configuration that passes Validate
+func valid() *Config {
+	c := Default()
+	c.Mongo = MongoConfig{Host: "localhost:27017", Username: "admin", Password: "secret"}
+	c.Algs1API = "http://localhost:9001/schedule"
+	c.Algs2API = "http://localhost:9002/predict"
+	c.JWTSecret = "secret"
+	c.APIHash = "abc"
+	c.fillEnvironmentDefaults()
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Expected a valid configuration. Got %v", err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		change   func(c *Config)
+		problems []string
+	}{
+		{"production without Mongo", func(c *Config) {
+			c.Environment = Production
+			c.Mongo = MongoConfig{}
+		}, []string{"MONGO_PRODUCTION_HOST is required", "MONGO_PRODUCTION_PASSWORD is required", "CORS_ALLOWED_ORIGINS can't be *"}},
+		{"any origin in production", func(c *Config) {
+			c.Environment = Production
+			c.Mongo = MongoConfig{Host: "cluster", Username: "admin", Password: "secret"}
+		}, []string{"CORS_ALLOWED_ORIGINS can't be *"}},
+		{"unknown environment", func(c *Config) {
+			c.Environment = "staging"
+		}, []string{`ENVIRONMENT must be "development" or "production"`}},
+		{"long preflight cache", func(c *Config) {
+			c.CORS.MaxAge.Duration = time.Hour
+		}, []string{"CORS_MAX_AGE must be between 0 and 10m"}},
+		{"reset link that isn't http", func(c *Config) {
+			c.PasswordReset.URL = "ftp://example.com/reset"
+		}, []string{"PASSWORD_RESET_URL must be an http or https URL"}},
+		{"SMTP without a server", func(c *Config) {
+			c.Mail.Driver = MailSMTP
+		}, []string{"SMTP_HOST is required"}},
+		{"half of TLS", func(c *Config) {
+			c.Server.TLSCertFile = "cert.pem"
+		}, []string{"TLS_CERT_FILE and TLS_KEY_FILE must be set together"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := valid()
+			test.change(c)
+			err := c.Validate()
+			if err == nil {
+				t.Fatal("Expected the configuration to be refused")
+			}
+			for _, problem := range test.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("Expected %q to be reported. Got %v", problem, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/modules/middleware"
+)
+
+// exposedHeaders are the response headers the frontend is allowed to read
+var exposedHeaders = []string{
+	middleware.RequestIDHeader, "Deprecation", "Sunset", "Link", "ETag",
+	"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+}
+
+// withCORS wraps the handler with the CORS policy of the configuration
+func withCORS(cors config.CORSConfig, next http.Handler) http.Handler {
+	options := []handlers.CORSOption{
+		handlers.AllowedOrigins(cors.AllowedOrigins),
+		// Without a validator gorilla allows every origin when the list is empty
+		handlers.AllowedOriginValidator(func(origin string) bool {
+			for _, allowed := range cors.AllowedOrigins {
+				if allowed == "*" || allowed == origin {
+					return true
+				}
+			}
+			return false
+		}),
+		handlers.AllowedMethods(cors.AllowedMethods),
+		handlers.AllowedHeaders(cors.AllowedHeaders),
+		handlers.ExposedHeaders(exposedHeaders),
+		handlers.MaxAge(int(cors.MaxAge.Seconds())),
+	}
+	if cors.AllowCredentials {
+		options = append(options, handlers.AllowCredentials())
+	}
+	return handlers.CORS(options...)(next)
+}
+
+// blockedByCORS lists the routes of the router, as "METHOD path", whose method the CORS policy
+// doesn't allow, so browsers on other origins can't call them
+func blockedByCORS(router *mux.Router, allowedMethods []string) []string {
+	allowed := map[string]bool{}
+	for _, method := range allowedMethods {
+		allowed[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+
+	var blocked []string
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if !allowed[method] {
+				blocked = append(blocked, method+" "+template)
+			}
+		}
+		return nil
+	})
+	sort.Strings(blocked)
+	return blocked
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+)
+
+// TestCORSMethods fails when a route uses a method the default CORS policy doesn't allow
+func TestCORSMethods(t *testing.T) {
+	router := mux.NewRouter()
+	registerRoutes(router)
+
+	for _, environment := range []string{config.Development, config.Production} {
+		for _, route := range blockedByCORS(router, config.DefaultCORS(environment).AllowedMethods) {
+			t.Errorf("Route %s isn't allowed by the %s CORS policy", route, environment)
+		}
+	}
+
+	blocked := blockedByCORS(router, []string{"GET", "POST"})
+	found := false
+	for _, route := range blocked {
+		found = found || route == "DELETE /api/v1/courses/{courseShortHand}"
+	}
+	if !found {
+		t.Errorf("Expected DELETE /api/v1/courses/{courseShortHand} to be reported. Got %v", blocked)
+	}
+}
+
+// TestCORSOrigins checks that only the configured origins get CORS headers
+func TestCORSOrigins(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	production := config.DefaultCORS(config.Production)
+	listed := production
+	listed.AllowedOrigins = []string{"https://schedule.example.com"}
+
+	tests := []struct {
+		cors     config.CORSConfig
+		origin   string
+		expected string
+	}{
+		{config.DefaultCORS(config.Development), "http://localhost:3000", "*"},
+		{production, "https://evil.example.com", ""},
+		{listed, "https://schedule.example.com", "https://schedule.example.com"},
+		{listed, "https://evil.example.com", ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("OPTIONS", "/courses/CSC225", nil)
+		req.Header.Set("Origin", test.origin)
+		req.Header.Set("Access-Control-Request-Method", "DELETE")
+		req.Header.Set("Access-Control-Request-Headers", "apikey")
+		response := httptest.NewRecorder()
+		withCORS(test.cors, ok).ServeHTTP(response, req)
+		if got := response.Header().Get("Access-Control-Allow-Origin"); got != test.expected {
+			t.Errorf("Expected %s to be allowed as %q. Got %q", test.origin, test.expected, got)
+		}
+	}
+}
@@ -3,13 +3,17 @@ module github.com/SENG-499-Company2-B01/Backend
 go 1.20
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/gorilla/mux v1.8.0
 	github.com/joho/godotenv v1.5.1
 	go.mongodb.org/mongo-driver v1.11.6
-	golang.org/x/text v0.3.8
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/felixge/httpsnoop v1.0.1 // indirect
+require (
+	github.com/felixge/httpsnoop v1.0.1 // indirect
+	golang.org/x/text v0.3.8 // indirect
+)
 
 require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible
@@ -22,6 +26,6 @@ require (
 	github.com/xdg-go/scram v1.1.1 // indirect
 	github.com/xdg-go/stringprep v1.0.3 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
-	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d // indirect
+	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
 )
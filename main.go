@@ -9,26 +9,35 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/SENG-499-Company2-B01/Backend/config"
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/migrations"
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/blocks"
+	"github.com/SENG-499-Company2-B01/Backend/modules/buildings"
 	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
 	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
 	"github.com/SENG-499-Company2-B01/Backend/modules/health"
+	"github.com/SENG-499-Company2-B01/Backend/modules/mail"
 	"github.com/SENG-499-Company2-B01/Backend/modules/middleware"
+	"github.com/SENG-499-Company2-B01/Backend/modules/notifications"
 	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+	"github.com/SENG-499-Company2-B01/Backend/modules/search"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
 	"github.com/SENG-499-Company2-B01/Backend/modules/users"
 
-	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var client *mongo.Client
-var algs1_api string
-var algs2_api string
+var cfg *config.Config
+var notifier *notifications.Service
 
-func init() {
+// setup starts the logger, loads the configuration and connects to MongoDB. It is called by main
+// rather than being init, so the tests of this package can build the routes without a database.
+func setup() {
 	// Get the current working directory
 	var err error
 	dir, err := os.Getwd()
@@ -58,81 +67,128 @@ func init() {
 	// Print a success message to the console
 	logger.Info("Logger initialized successfully!")
 
-	// Construct the path to the .env file
-	envPath := filepath.Join(dir, ".env")
-
-	// Load the .env file
-	err = godotenv.Load(envPath)
+	// Load and validate the configuration, reporting every problem at once
+	cfg, err = config.Load(dir)
 	if err != nil {
-		log.Fatal("Error loading .env file:", err)
+		log.Fatal("Invalid configuration:\n", err)
 	}
 
-	algs1_api = os.Getenv("ALGS1_API")
-	algs2_api = os.Getenv("ALGS2_API")
-
-	if os.Getenv("ENVIRONMENT") == "development" {
-		// Load the environment variables locally
-		mongohost := os.Getenv("MONGO_LOCAL_HOST")
-		mongoUsername := os.Getenv("MONGO_LOCAL_USERNAME")
-		mongoPassword := os.Getenv("MONGO_LOCAL_PASSWORD")
+	// Connect to MongoDB
+	client, err = mongo.Connect(context.Background(), cfg.MongoClientOptions())
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		// Set up the MongoDB client with SCRAM-SHA-1 authentication
-		clientOptions := options.Client().ApplyURI("mongodb://" + mongohost).
-			SetAuth(options.Credential{
-				Username:      mongoUsername,
-				Password:      mongoPassword,
-				AuthMechanism: "SCRAM-SHA-256",
-			})
+	// Check the connection
+	err = client.Ping(context.Background(), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		client, err = mongo.Connect(context.TODO(), clientOptions)
-		if err != nil {
-			log.Fatal(err)
-		}
+	logger.Info("Connected to MongoDB successfully!")
 
-		// Check the connection
-		err = client.Ping(context.TODO(), nil)
+	if cfg.MigrateOnStartup {
+		_, err = migrations.Run(context.Background(), client.Database("schedule_db"))
 		if err != nil {
-			log.Fatal(err)
+			log.Fatal("Error applying migrations: ", err)
 		}
+	}
 
-	} else if os.Getenv("ENVIRONMENT") == "production" {
-		// Load the environment variables locally
-		mongoUsername := os.Getenv("MONGO_PRODUCTION_USERNAME")
-		mongoPassword := os.Getenv("MONGO_PRODUCTION_PASSWORD")
-		mongoHost := os.Getenv("MONGO_PRODUCTION_HOST")
+	// Give a new database its first admins
+	db := client.Database("schedule_db")
+	granted, err := users.BootstrapAdmins(context.Background(), db.Collection("users"), db.Collection("role_changes"), cfg.Admins)
+	if err != nil {
+		log.Fatal("Error bootstrapping admins: ", err)
+	}
+	for _, username := range granted {
+		logger.Info("Made " + username + " an admin from the configuration")
+	}
 
-		// Use the MongoDB Atlas connection string
-		connectionString := fmt.Sprintf("mongodb+srv://%s:%s@%s/?retryWrites=true&w=majority", mongoUsername, mongoPassword, mongoHost)
+	// Queue notifications of schedule and preference changes, main starts delivering them
+	notifier = newNotifier()
+	events.Subscribe(notifier.Handle)
+}
 
-		// Set up client options
-		clientOptions := options.Client().ApplyURI(connectionString)
+// loginGuard limits failed logins as configured
+func loginGuard() *users.LoginGuard {
+	return &users.LoginGuard{
+		Attempts:   client.Database("schedule_db").Collection("login_attempts"),
+		Events:     client.Database("schedule_db").Collection(audit.Collection),
+		Policy:     cfg.Login,
+		TrustProxy: cfg.Server.TrustProxyHeaders,
+	}
+}
 
-		// Connect to MongoDB
-		client, err = mongo.Connect(context.Background(), clientOptions)
-		if err != nil {
-			log.Fatal(err)
+// newMailer returns the mailer of the configured driver
+func newMailer(mailConfig config.MailConfig) mail.Mailer {
+	switch mailConfig.Driver {
+	case config.MailSMTP:
+		return mail.SMTPMailer{
+			Host:     mailConfig.SMTP.Host,
+			Port:     mailConfig.SMTP.Port,
+			Username: mailConfig.SMTP.Username,
+			Password: mailConfig.SMTP.Password,
+			From:     mailConfig.From,
 		}
+	case config.MailFile:
+		return mail.FileMailer{Dir: mailConfig.Dir, From: mailConfig.From}
+	}
+	return mail.LogMailer{}
+}
 
-		// Ping the MongoDB server to check the connection
-		err = client.Ping(context.Background(), nil)
-		if err != nil {
-			log.Fatal(err)
-		}
+// passwordResets emails reset links as configured
+func passwordResets() *users.PasswordResets {
+	return &users.PasswordResets{
+		Tokens: client.Database("schedule_db").Collection("password_resets"),
+		Events: client.Database("schedule_db").Collection(audit.Collection),
+		Mailer: newMailer(cfg.Mail),
+		URL:    cfg.PasswordReset.URL,
+		TTL:    cfg.PasswordReset.TTL.Duration,
 	}
+}
 
-	logger.Info("Connected to MongoDB successfully!")
+// newNotifier delivers notifications through the configured channels
+func newNotifier() *notifications.Service {
+	db := client.Database("schedule_db")
+	channels := map[string]notifications.Channel{
+		notifications.Email: notifications.EmailChannel{Mailer: newMailer(cfg.Mail)},
+		notifications.Inbox: notifications.InboxChannel{Inbox: db.Collection(notifications.InboxCollection)},
+	}
+	if cfg.Notifications.WebhookURL != "" {
+		channels[notifications.Webhook] = notifications.WebhookChannel{URL: cfg.Notifications.WebhookURL, Secret: cfg.Notifications.WebhookSecret}
+	}
+	return &notifications.Service{
+		Users:        db.Collection("users"),
+		Outbox:       db.Collection(notifications.OutboxCollection),
+		Preferences:  db.Collection(notifications.PreferencesCollection),
+		Channels:     channels,
+		MaxAttempts:  cfg.Notifications.MaxAttempts,
+		RetryBackoff: cfg.Notifications.RetryBackoff.Duration,
+		PollInterval: cfg.Notifications.PollInterval.Duration,
+	}
 }
 
 func handleUserRequests(router *mux.Router) {
 	router.Use(func(next http.Handler) http.Handler {
-		return middleware.Users_API_Access_Control(next, client.Database("schedule_db").Collection("users"))
+		return middleware.Users_API_Access_Control(next, client.Database("schedule_db").Collection("users"), cfg)
 	})
 
 	// AUTHENTICATION
 	router.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
-		users.SignIn(w, r, client.Database("schedule_db").Collection("users"))
+		users.SignIn(w, r, client.Database("schedule_db").Collection("users"), loginGuard(), cfg.JWTSecret)
 	}).Methods(http.MethodPost)
 
+	// Password resets need the frontend page the emailed links open
+	if cfg.PasswordReset.Enabled() {
+		router.HandleFunc("/password/forgot", func(w http.ResponseWriter, r *http.Request) {
+			users.ForgotPassword(w, r, client.Database("schedule_db").Collection("users"), passwordResets())
+		}).Methods(http.MethodPost)
+
+		router.HandleFunc("/password/reset", func(w http.ResponseWriter, r *http.Request) {
+			users.ResetPassword(w, r, client.Database("schedule_db").Collection("users"), passwordResets(), loginGuard())
+		}).Methods(http.MethodPost)
+	}
+
 	router.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
 		users.Logout(w, r, client.Database("schedule_db").Collection("users"))
 	}).Methods(http.MethodPost)
@@ -158,6 +214,56 @@ func handleUserRequests(router *mux.Router) {
 		users.UpdateUser(w, r, client.Database("schedule_db").Collection("users"))
 	}).Methods(http.MethodPut)
 
+	router.HandleFunc("/users/{username}", func(w http.ResponseWriter, r *http.Request) {
+		users.PatchUser(w, r, client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodPatch)
+
+	router.HandleFunc("/users/{username}", func(w http.ResponseWriter, r *http.Request) {
+		users.DeactivateUser(w, r, client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/users/{username}/unlock", func(w http.ResponseWriter, r *http.Request) {
+		users.UnlockUser(w, r, loginGuard())
+	}).Methods(http.MethodPost)
+
+	// Admin rights
+	router.HandleFunc("/users/{username}/admin", func(w http.ResponseWriter, r *http.Request) {
+		users.GetAdminRole(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("role_changes"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/users/{username}/admin", func(w http.ResponseWriter, r *http.Request) {
+		users.GrantAdmin(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("role_changes"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/users/{username}/admin", func(w http.ResponseWriter, r *http.Request) {
+		users.RevokeAdmin(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("role_changes"))
+	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/users/{username}/reactivate", func(w http.ResponseWriter, r *http.Request) {
+		users.ReactivateUser(w, r, client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodPost)
+
+	// Notifications
+	router.HandleFunc("/users/{username}/notifications", func(w http.ResponseWriter, r *http.Request) {
+		notifications.GetNotifications(w, r, client.Database("schedule_db").Collection(notifications.InboxCollection))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/users/{username}/notifications/{id}/read", func(w http.ResponseWriter, r *http.Request) {
+		notifications.MarkNotificationRead(w, r, client.Database("schedule_db").Collection(notifications.InboxCollection))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/users/{username}/notification-preferences", func(w http.ResponseWriter, r *http.Request) {
+		notifications.GetPreferences(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection(notifications.PreferencesCollection))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/users/{username}/notification-preferences", func(w http.ResponseWriter, r *http.Request) {
+		notifications.UpdatePreferences(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection(notifications.PreferencesCollection))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/users/deactivated/sections", func(w http.ResponseWriter, r *http.Request) {
+		schedules.GetOrphanedSections(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodGet)
+
 }
 
 func handleClassroomRequests(router *mux.Router) {
@@ -178,9 +284,40 @@ func handleClassroomRequests(router *mux.Router) {
 		classrooms.UpdateClassroom(w, r, client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodPut)
 
+	router.HandleFunc("/classrooms/{building}/{room}", func(w http.ResponseWriter, r *http.Request) {
+		classrooms.PatchClassroom(w, r, client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodPatch)
+
 	router.HandleFunc("/classrooms/{building}/{room}", func(w http.ResponseWriter, r *http.Request) {
 		classrooms.DeleteClassroom(w, r, client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/classrooms/{building}/{room}/rename", func(w http.ResponseWriter, r *http.Request) {
+		classrooms.RenameClassroom(w, r, client.Database("schedule_db").Collection("classrooms"), client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection(audit.Collection))
+	}).Methods(http.MethodPost)
+}
+
+func handleBuildingRequests(router *mux.Router) {
+	// Buildings CRUD Operations
+	router.HandleFunc("/buildings", func(w http.ResponseWriter, r *http.Request) {
+		buildings.CreateBuilding(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/buildings", func(w http.ResponseWriter, r *http.Request) {
+		buildings.GetBuildings(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/buildings/{code}", func(w http.ResponseWriter, r *http.Request) {
+		buildings.GetBuilding(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/buildings/{code}", func(w http.ResponseWriter, r *http.Request) {
+		buildings.UpdateBuilding(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/buildings/{code}", func(w http.ResponseWriter, r *http.Request) {
+		buildings.DeleteBuilding(w, r, client.Database("schedule_db").Collection("buildings"), client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodDelete)
 }
 
 func handleCourseRequests(router *mux.Router) {
@@ -204,6 +341,64 @@ func handleCourseRequests(router *mux.Router) {
 	router.HandleFunc("/courses/{courseShortHand}", func(w http.ResponseWriter, r *http.Request) {
 		courses.UpdateCourse(w, r, client.Database("schedule_db").Collection("courses"))
 	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/courses/{courseShortHand}", func(w http.ResponseWriter, r *http.Request) {
+		courses.PatchCourse(w, r, client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodPatch)
+
+	router.HandleFunc("/courses/{courseShortHand}/prerequisites", func(w http.ResponseWriter, r *http.Request) {
+		courses.GetPrerequisites(w, r, client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/courses/{courseShortHand}/dependents", func(w http.ResponseWriter, r *http.Request) {
+		courses.GetDependents(w, r, client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodGet)
+}
+
+func handleTermRequests(router *mux.Router) {
+	// Terms CRUD Operations
+	router.HandleFunc("/terms", func(w http.ResponseWriter, r *http.Request) {
+		terms.CreateTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/terms", func(w http.ResponseWriter, r *http.Request) {
+		terms.GetTerms(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/terms/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		terms.GetTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/terms/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		terms.UpdateTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/terms/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		terms.DeleteTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodDelete)
+}
+
+func handleBlockRequests(router *mux.Router) {
+	// Blocks CRUD Operations
+	router.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
+		blocks.CreateBlock(w, r, client.Database("schedule_db").Collection("blocks"), client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
+		blocks.GetBlocks(w, r, client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/blocks/{code}", func(w http.ResponseWriter, r *http.Request) {
+		blocks.GetBlock(w, r, client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/blocks/{code}", func(w http.ResponseWriter, r *http.Request) {
+		blocks.UpdateBlock(w, r, client.Database("schedule_db").Collection("blocks"), client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/blocks/{code}", func(w http.ResponseWriter, r *http.Request) {
+		blocks.DeleteBlock(w, r, client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodDelete)
 }
 
 func handleScheduleRequests(router *mux.Router) {
@@ -211,7 +406,8 @@ func handleScheduleRequests(router *mux.Router) {
 	router.HandleFunc("/schedules/{year}/{term}/generate", func(w http.ResponseWriter, r *http.Request) {
 		schedules.GenerateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"),
 			client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("courses"),
-			client.Database("schedule_db").Collection("classrooms"), algs1_api, algs2_api)
+			client.Database("schedule_db").Collection("classrooms"), client.Database("schedule_db").Collection("terms"),
+			client.Database("schedule_db").Collection("blocks"), cfg.Algs1API, cfg.Algs2API)
 	}).Methods(http.MethodPost)
 
 	// Schedules Read Operations
@@ -220,45 +416,118 @@ func handleScheduleRequests(router *mux.Router) {
 	}).Methods(http.MethodGet)
 
 	router.HandleFunc("/schedules/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
-		schedules.GetSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"))
+		schedules.GetSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/schedules/{year}/{term}/clashes", func(w http.ResponseWriter, r *http.Request) {
+		schedules.GetClashes(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/schedules/{year}/{term}/validation", func(w http.ResponseWriter, r *http.Request) {
+		schedules.ValidateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodGet)
 
 	// Schedules Update Operation
 	router.HandleFunc("/schedules/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
-		schedules.UpdateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"))
+		schedules.UpdateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("terms"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodPut)
 
+	router.HandleFunc("/schedules/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		schedules.PatchSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("terms"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodPatch)
+
 	// Previous Schedule Operations
 	router.HandleFunc("/schedules/prev", func(w http.ResponseWriter, r *http.Request) {
 		schedules.GetSchedules(w, r, client.Database("schedule_db").Collection("previous_schedules"))
 	}).Methods(http.MethodGet)
 
 	router.HandleFunc("/schedules/prev", func(w http.ResponseWriter, r *http.Request) {
-		schedules.ApproveSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("previous_schedules"))
+		schedules.ApproveSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("previous_schedules"), client.Database("schedule_db").Collection("terms"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodPost)
 }
 
+func handleAuditRequests(router *mux.Router) {
+	router.HandleFunc("/audit", func(w http.ResponseWriter, r *http.Request) {
+		audit.GetEvents(w, r, client.Database("schedule_db").Collection(audit.Collection))
+	}).Methods(http.MethodGet)
+}
+
+func handleSearchRequests(router *mux.Router) {
+	router.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		search.Search(w, r, client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("users"),
+			client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodGet)
+}
+
+// registerRoutes adds every route of the API, each must be described in modules/openapi
+func registerRoutes(router *mux.Router) {
+	// This route will be used by the cloud server to test its health, it only ever returns 200 OK
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		health.CheckHealth(w, r)
+	}).Methods(http.MethodGet)
+
+	mountVersions(router)
+}
+
 func main() {
 	// // Example Logging messages
 	// logger.Info("This is an info message")
 	// logger.Warning("This is a warning message")
 	// logger.Error(fmt.Errorf("This is an error message"))
 
+	setup()
+
+	// Run a subcommand instead of the server if one was given, e.g. "app migrate"
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	router := mux.NewRouter()
-	headersOk := handlers.AllowedHeaders([]string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"})
-	originsOk := handlers.AllowedOrigins([]string{"*"})
-	methodsOk := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS"})
-	handleUserRequests(router)
-	handleClassroomRequests(router)
-	handleCourseRequests(router)
-	handleScheduleRequests(router)
 
-	// This route will be used by the cloud server to test its health, it only ever returns 200 OK
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		health.CheckHealth(w, r)
-	}).Methods(http.MethodGet)
+	// Tag every request first so errors from the other middleware carry the ID too
+	router.Use(middleware.RequestID)
+	registerRoutes(router)
+
+	for _, route := range blockedByCORS(router, cfg.CORS.AllowedMethods) {
+		logger.Warning("CORS_ALLOWED_METHODS doesn't allow " + route + ", browsers on other origins can't call it")
+	}
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		logger.Warning("CORS_ALLOWED_ORIGINS is empty, browsers on other origins can't call the API")
+	}
+	if !cfg.PasswordReset.Enabled() {
+		logger.Warning("PASSWORD_RESET_URL is not set, password resets are turned off")
+	}
+	if cfg.Mail.Driver == config.MailLog && cfg.Environment == config.Production {
+		logger.Warning("SMTP_HOST is not set, emails are written to the log instead of being sent")
+	}
+
+	server := newServer(cfg.Server, withCORS(cfg.CORS, router))
+
+	// Deliver queued notifications while the server runs
+	ctx, cancel := context.WithCancel(context.Background())
+	delivered := make(chan struct{})
+	go func() {
+		notifier.Run(ctx)
+		close(delivered)
+	}()
+	stopNotifier := func(shutdown context.Context) error {
+		cancel()
+		select {
+		case <-delivered:
+			return nil
+		case <-shutdown.Done():
+			return shutdown.Err()
+		}
+	}
 
-	log.Fatal(http.ListenAndServe(":8000", handlers.CORS(originsOk, headersOk, methodsOk)(router)))
+	err := runServer(server, cfg.Server, stopNotifier)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 // // Example handle request
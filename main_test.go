@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+)
+
+// TestMain gives the routes a configuration and a client that never connects, so building them
+// doesn't need setup or a database
+func TestMain(m *testing.M) {
+	logger.InitLogger(io.Discard, io.Discard, io.Discard, io.Discard)
+
+	cfg = config.Default()
+	cfg.ValidateRequests = true
+	cfg.PasswordReset.URL = "http://localhost:3000/reset-password"
+
+	var err error
+	client, err = mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(m.Run())
+}
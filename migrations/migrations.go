@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+)
+
+// Collection that records which migrations have been applied
+const historyCollection = "schema_migrations"
+
+// Migration is a single versioned change to the database
+type Migration struct {
+	Version     int                                                 `json:"version"`
+	Description string                                              `json:"description"`
+	Up          func(ctx context.Context, db *mongo.Database) error `json:"-"`
+}
+
+// AppliedMigration is the record stored once a migration has run
+type AppliedMigration struct {
+	Version     int       `json:"version" bson:"version"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// registered holds every known migration, see All
+var registered []Migration
+
+// register adds a migration to the registry, it is called from the init functions of this package
+func register(migration Migration) {
+	registered = append(registered, migration)
+}
+
+// All returns every known migration ordered by version
+func All() []Migration {
+	all := make([]Migration, len(registered))
+	copy(all, registered)
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Version < all[j].Version
+	})
+	return all
+}
+
+// Run applies every migration that has not been applied yet, in version order,
+// and returns the migrations that were applied
+func Run(ctx context.Context, db *mongo.Database) ([]Migration, error) {
+	history := db.Collection(historyCollection)
+
+	// A unique version stops two instances from recording the same migration twice
+	_, err := history.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating migration history index: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range All() {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("Applying migration %d: %s", migration.Version, migration.Description))
+		err := migration.Up(ctx, db)
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		_, err = history.InsertOne(ctx, AppliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return ran, fmt.Errorf("error recording migration %d: %w", migration.Version, err)
+		}
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func Status(ctx context.Context, db *mongo.Database) ([]MigrationStatus, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range All() {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// appliedVersions reads the migration history keyed by version
+func appliedVersions(ctx context.Context, db *mongo.Database) (map[int]AppliedMigration, error) {
+	cursor, err := db.Collection(historyCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading migration history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	applied := make(map[int]AppliedMigration)
+	for cursor.Next(ctx) {
+		var record AppliedMigration
+		err := cursor.Decode(&record)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding migration history: %w", err)
+		}
+		applied[record.Version] = record
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error reading migration history: %w", err)
+	}
+	return applied, nil
+}
+
+// createIndexes creates the given indexes on a collection, creating the collection if needed
+func createIndexes(ctx context.Context, db *mongo.Database, collection string, indexes ...mongo.IndexModel) error {
+	_, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("error creating indexes on %s: %w", collection, err)
+	}
+	return nil
+}
+
+// setValidator applies a JSON schema validator to a collection, creating the collection if needed.
+// Moderate validation leaves existing documents that do not match the schema untouched.
+func setValidator(ctx context.Context, db *mongo.Database, collection string, schema bson.M) error {
+	validator := bson.M{"$jsonSchema": schema}
+
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}).Err()
+
+	// NamespaceNotFound, the collection has not been created yet
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == 26 {
+		err = db.CreateCollection(ctx, collection, options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel("moderate"))
+	}
+	if err != nil {
+		return fmt.Errorf("error setting validator on %s: %w", collection, err)
+	}
+	return nil
+}
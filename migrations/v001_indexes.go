@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     1,
+		Description: "create unique and lookup indexes",
+		Up:          createInitialIndexes,
+	})
+}
+
+// realEmail matches the users with an email address. The seed data has users without one, whose
+// email is "-" or empty, and those can't be unique. Partial indexes can't use $ne, but every
+// address sorts after "-".
+var realEmail = bson.M{"email": bson.M{"$gt": "-"}}
+
+// createInitialIndexes enforces the uniqueness the handlers check for and indexes the common lookups
+func createInitialIndexes(ctx context.Context, db *mongo.Database) error {
+	err := createIndexes(ctx, db, "users",
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true).SetName("username_unique")},
+		mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true).SetName("email_unique").SetPartialFilterExpression(realEmail)},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = createIndexes(ctx, db, "courses",
+		mongo.IndexModel{Keys: bson.D{{Key: "shorthand", Value: 1}}, Options: options.Index().SetUnique(true).SetName("shorthand_unique")},
+		mongo.IndexModel{Keys: bson.D{{Key: "terms_offered", Value: 1}}, Options: options.Index().SetName("terms_offered")},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = createIndexes(ctx, db, "classrooms",
+		mongo.IndexModel{Keys: bson.D{{Key: "building", Value: 1}, {Key: "room", Value: 1}}, Options: options.Index().SetUnique(true).SetName("building_room_unique")},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range []string{"draft_schedules", "previous_schedules"} {
+		err = createIndexes(ctx, db, collection,
+			mongo.IndexModel{Keys: bson.D{{Key: "year", Value: 1}, {Key: "terms.term", Value: 1}}, Options: options.Index().SetName("year_term")},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version:     2,
+		Description: "add JSON schema validators",
+		Up:          addValidators,
+	})
+}
+
+// Schema fragments shared by the validators below. The Go structs encode nil slices
+// and maps as null, so optional arrays and objects also accept null.
+var (
+	stringType      = bson.M{"bsonType": "string"}
+	numberType      = bson.M{"bsonType": "number"}
+	boolType        = bson.M{"bsonType": "bool"}
+	objectType      = bson.M{"bsonType": bson.A{"object", "null"}}
+	stringArrayType = bson.M{"bsonType": bson.A{"array", "null"}, "items": stringType}
+	nestedArrayType = bson.M{"bsonType": bson.A{"array", "null"}, "items": stringArrayType}
+)
+
+// scheduleSchema is shared by the draft and previous schedule collections
+var scheduleSchema = bson.M{
+	"bsonType": "object",
+	"required": bson.A{"year", "terms"},
+	"properties": bson.M{
+		"year": numberType,
+		"terms": bson.M{
+			"bsonType": bson.A{"array", "null"},
+			"items": bson.M{
+				"bsonType": "object",
+				"required": bson.A{"term"},
+				"properties": bson.M{
+					"term":    stringType,
+					"courses": bson.M{"bsonType": bson.A{"array", "null"}},
+				},
+			},
+		},
+	},
+}
+
+// addValidators attaches a JSON schema validator to each collection in schedule_db
+func addValidators(ctx context.Context, db *mongo.Database) error {
+	err := setValidator(ctx, db, "users", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"username", "email", "password"},
+		"properties": bson.M{
+			"username": stringType,
+			"email":    stringType,
+			// The seed script stores bcrypt hashes as binary
+			"password":      bson.M{"bsonType": bson.A{"string", "binData"}},
+			"name":          stringType,
+			"isAdmin":       boolType,
+			"peng":          boolType,
+			"pref_approved": boolType,
+			"max_courses":   numberType,
+			"course_pref":   stringArrayType,
+			"time_pref":     objectType,
+			"available":     objectType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = setValidator(ctx, db, "courses", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"shorthand"},
+		"properties": bson.M{
+			"shorthand":     stringType,
+			"name":          stringType,
+			"prerequisites": nestedArrayType,
+			"corequisites":  nestedArrayType,
+			"terms_offered": stringArrayType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = setValidator(ctx, db, "classrooms", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"building", "room"},
+		"properties": bson.M{
+			"building": stringType,
+			"room":     stringType,
+			"capacity": numberType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range []string{"draft_schedules", "previous_schedules"} {
+		err = setValidator(ctx, db, collection, scheduleSchema)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
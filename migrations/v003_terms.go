@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     3,
+		Description: "add terms collection",
+		Up:          addTerms,
+	})
+}
+
+// addTerms creates the academic calendar collection with one document per year and term
+func addTerms(ctx context.Context, db *mongo.Database) error {
+	dateRangeType := bson.M{
+		"bsonType": "object",
+		"required": bson.A{"start", "end"},
+		"properties": bson.M{
+			"start": bson.M{"bsonType": "date"},
+			"end":   bson.M{"bsonType": "date"},
+		},
+	}
+
+	err := setValidator(ctx, db, "terms", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"year", "term", "start_date", "end_date"},
+		"properties": bson.M{
+			"year":           numberType,
+			"term":           stringType,
+			"name":           stringType,
+			"start_date":     bson.M{"bsonType": "date"},
+			"end_date":       bson.M{"bsonType": "date"},
+			"reading_breaks": bson.M{"bsonType": bson.A{"array", "null"}, "items": dateRangeType},
+			"holidays":       bson.M{"bsonType": bson.A{"array", "null"}},
+			"teaching_days":  stringArrayType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, db, "terms",
+		mongo.IndexModel{Keys: bson.D{{Key: "year", Value: 1}, {Key: "term", Value: 1}}, Options: options.Index().SetUnique(true).SetName("year_term_unique")},
+	)
+}
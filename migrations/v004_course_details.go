@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version:     4,
+		Description: "add hours, sections, enrollment limits and requirements to courses",
+		Up:          addCourseDetails,
+	})
+}
+
+// addCourseDetails extends the courses validator with the new fields and backfills the
+// values that used to be hardcoded when generating a schedule
+func addCourseDetails(ctx context.Context, db *mongo.Database) error {
+	err := setValidator(ctx, db, "courses", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"shorthand"},
+		"properties": bson.M{
+			"shorthand":     stringType,
+			"name":          stringType,
+			"prerequisites": nestedArrayType,
+			"corequisites":  nestedArrayType,
+			"terms_offered": stringArrayType,
+			"hours": bson.M{
+				"bsonType": "object",
+				"properties": bson.M{
+					"lecture":  bson.M{"bsonType": "number", "minimum": 0},
+					"lab":      bson.M{"bsonType": "number", "minimum": 0},
+					"tutorial": bson.M{"bsonType": "number", "minimum": 0},
+				},
+			},
+			"sections":          bson.M{"bsonType": "number", "minimum": 1},
+			"min_enroll":        bson.M{"bsonType": "number", "minimum": 0},
+			"max_enroll":        bson.M{"bsonType": "number", "minimum": 0},
+			"peng":              boolType,
+			"required_features": stringArrayType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	defaults := bson.D{
+		{Key: "hours", Value: bson.M{"lecture": 3, "lab": 0, "tutorial": 0}},
+		{Key: "sections", Value: 1},
+		{Key: "min_enroll", Value: 5},
+		{Key: "max_enroll", Value: 0},
+		{Key: "peng", Value: false},
+		{Key: "required_features", Value: bson.A{}},
+	}
+	courses := db.Collection("courses")
+	for _, field := range defaults {
+		_, err = courses.UpdateMany(ctx,
+			bson.M{field.Key: bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{field.Key: field.Value}},
+		)
+		if err != nil {
+			return fmt.Errorf("error backfilling courses.%s: %w", field.Key, err)
+		}
+	}
+	return nil
+}
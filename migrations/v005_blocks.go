@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     5,
+		Description: "add curriculum blocks collection",
+		Up:          addBlocks,
+	})
+}
+
+// addBlocks creates the collection of courses that should not be scheduled at the same time
+func addBlocks(ctx context.Context, db *mongo.Database) error {
+	err := setValidator(ctx, db, "blocks", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"code", "courses", "constraint"},
+		"properties": bson.M{
+			"code":       stringType,
+			"name":       stringType,
+			"term":       stringType,
+			"courses":    stringArrayType,
+			"constraint": bson.M{"enum": bson.A{"hard", "soft"}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, db, "blocks",
+		mongo.IndexModel{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true).SetName("code_unique")},
+		mongo.IndexModel{Keys: bson.D{{Key: "term", Value: 1}}},
+	)
+}
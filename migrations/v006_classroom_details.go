@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version:     6,
+		Description: "add room types, features and blackouts to classrooms",
+		Up:          addClassroomDetails,
+	})
+}
+
+// addClassroomDetails extends the classrooms validator with the new fields and marks the
+// existing classrooms as lecture rooms without features or blackouts
+func addClassroomDetails(ctx context.Context, db *mongo.Database) error {
+	err := setValidator(ctx, db, "classrooms", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"building", "room"},
+		"properties": bson.M{
+			"building":  stringType,
+			"room":      stringType,
+			"capacity":  numberType,
+			"room_type": bson.M{"enum": bson.A{"lecture", "lab", "seminar"}},
+			"features":  stringArrayType,
+			"blackouts": bson.M{
+				"bsonType": bson.A{"array", "null"},
+				"items": bson.M{
+					"bsonType": "object",
+					"required": bson.A{"days", "start_time", "end_time"},
+					"properties": bson.M{
+						"days":       stringArrayType,
+						"start_time": stringType,
+						"end_time":   stringType,
+						"reason":     stringType,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	defaults := bson.D{
+		{Key: "room_type", Value: "lecture"},
+		{Key: "features", Value: bson.A{}},
+		{Key: "blackouts", Value: bson.A{}},
+	}
+	classrooms := db.Collection("classrooms")
+	for _, field := range defaults {
+		_, err = classrooms.UpdateMany(ctx,
+			bson.M{field.Key: bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{field.Key: field.Value}},
+		)
+		if err != nil {
+			return fmt.Errorf("error backfilling classrooms.%s: %w", field.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     7,
+		Description: "add buildings collection",
+		Up:          addBuildings,
+	})
+}
+
+// addBuildings creates the collection of building metadata, keyed by the code classrooms use
+func addBuildings(ctx context.Context, db *mongo.Database) error {
+	err := setValidator(ctx, db, "buildings", bson.M{
+		"bsonType": "object",
+		"required": bson.A{"code", "name"},
+		"properties": bson.M{
+			"code":   stringType,
+			"name":   stringType,
+			"campus": stringType,
+			"location": bson.M{
+				"bsonType": bson.A{"object", "null"},
+				"properties": bson.M{
+					"address":   stringType,
+					"latitude":  numberType,
+					"longitude": numberType,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, db, "buildings",
+		mongo.IndexModel{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true).SetName("code_unique")},
+	)
+}
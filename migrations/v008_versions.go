@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version:     8,
+		Description: "add versions to users, courses, classrooms and draft schedules",
+		Up:          addVersions,
+	})
+}
+
+// addVersions starts every existing document of the resources clients edit concurrently at
+// version 1, the ETag the API sends for it
+func addVersions(ctx context.Context, db *mongo.Database) error {
+	for _, name := range []string{"users", "courses", "classrooms", "draft_schedules"} {
+		_, err := db.Collection(name).UpdateMany(ctx,
+			bson.M{"version": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return fmt.Errorf("error backfilling %s.version: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     9,
+		Description: "index the audit log and role changes",
+		Up:          indexAuditLog,
+	})
+}
+
+// indexAuditLog indexes the filters of GET /audit and the role history of a user, newest first
+func indexAuditLog(ctx context.Context, db *mongo.Database) error {
+	err := createIndexes(ctx, db, "audit_events",
+		mongo.IndexModel{Keys: bson.D{{Key: "at", Value: -1}}, Options: options.Index().SetName("at")},
+		mongo.IndexModel{Keys: bson.D{{Key: "resource_type", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "at", Value: -1}}, Options: options.Index().SetName("resource_at")},
+		mongo.IndexModel{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "at", Value: -1}}, Options: options.Index().SetName("actor_at")},
+	)
+	if err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, db, "role_changes",
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}, {Key: "at", Value: 1}}, Options: options.Index().SetName("username_at")},
+	)
+}
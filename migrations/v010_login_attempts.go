@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     10,
+		Description: "index failed logins and expire them",
+		Up:          indexLoginAttempts,
+	})
+}
+
+// indexLoginAttempts keeps one record per username or address, removed by Mongo once it expires
+func indexLoginAttempts(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db, "login_attempts",
+		mongo.IndexModel{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true).SetName("key_unique")},
+		mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl")},
+	)
+}
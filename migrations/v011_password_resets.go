@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     11,
+		Description: "index password reset tokens and expire them",
+		Up:          indexPasswordResets,
+	})
+}
+
+// indexPasswordResets finds tokens by their hash and has Mongo remove them once they expire
+func indexPasswordResets(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db, "password_resets",
+		mongo.IndexModel{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetName("token_hash_unique")},
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetName("username")},
+		mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl")},
+	)
+}
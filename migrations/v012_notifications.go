@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     12,
+		Description: "index the notification outbox, inboxes and preferences",
+		Up:          indexNotifications,
+	})
+}
+
+// indexNotifications finds the deliveries that are due, lists each inbox newest first and keeps
+// one set of preferences per user
+func indexNotifications(ctx context.Context, db *mongo.Database) error {
+	err := createIndexes(ctx, db, "notification_outbox",
+		mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}, Options: options.Index().SetName("status_next_attempt_at")},
+	)
+	if err != nil {
+		return err
+	}
+	err = createIndexes(ctx, db, "notifications",
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("username_created_at")},
+	)
+	if err != nil {
+		return err
+	}
+	return createIndexes(ctx, db, "notification_preferences",
+		mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true).SetName("username_unique")},
+	)
+}
@@ -0,0 +1,84 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// Error codes the frontend can branch on, the README lists when each is used
+const (
+	BadRequest          = "bad_request"
+	InvalidBody         = "invalid_body"
+	ValidationFailed    = "validation_failed"
+	Unauthorized        = "unauthorized"
+	InvalidCredentials  = "invalid_credentials"
+	InvalidToken        = "invalid_token"
+	AccountDeactivated  = "account_deactivated"
+	Forbidden           = "forbidden"
+	NotFound            = "not_found"
+	AlreadyExists       = "already_exists"
+	InUse               = "in_use"
+	LastAdmin           = "last_admin"
+	ConstraintViolation = "constraint_violation"
+	PreconditionFailed  = "precondition_failed"
+	PreconditionNeeded  = "precondition_required"
+	TooManyAttempts     = "too_many_attempts"
+	RateLimited         = "rate_limited"
+	BodyTooLarge        = "body_too_large"
+	Internal            = "internal_error"
+)
+
+// Error is the body of every error response
+type Error struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Details   []interface{} `json:"details"`
+	RequestID string        `json:"request_id"`
+}
+
+// Response wraps the error so clients can tell it apart from a resource
+type Response struct {
+	Error Error `json:"error"`
+}
+
+// Write - sends the error as JSON with the given status
+func Write(w http.ResponseWriter, r *http.Request, status int, code string, message string, details ...interface{}) {
+	if details == nil {
+		details = []interface{}{}
+	}
+	requestID, _ := helper.RequestIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Error: Error{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestID,
+	}})
+}
+
+// Problems - turns validation problems into error details
+func Problems(problems []string) []interface{} {
+	details := make([]interface{}, len(problems))
+	for i, problem := range problems {
+		details[i] = problem
+	}
+	return details
+}
+
+// WriteInvalidBody - sends the error for a request body that couldn't be read or decoded, 413 if
+// it was cut off for being larger than the server accepts
+func WriteInvalidBody(w http.ResponseWriter, r *http.Request, err error, message string, details ...interface{}) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Write(w, r, http.StatusRequestEntityTooLarge, BodyTooLarge, "Request body is larger than "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes.")
+		return
+	}
+	Write(w, r, http.StatusBadRequest, InvalidBody, message, details...)
+}
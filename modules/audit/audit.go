@@ -0,0 +1,297 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// Collection is where the events are stored. Nothing updates or deletes them.
+const Collection = "audit_events"
+
+// Event records a change made through the API
+type Event struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	At           time.Time          `json:"at" bson:"at"`
+	Actor        string             `json:"actor" bson:"actor"`
+	Action       string             `json:"action" bson:"action"`
+	ResourceType string             `json:"resource_type" bson:"resource_type"`
+	ResourceID   string             `json:"resource_id" bson:"resource_id"`
+	Method       string             `json:"method" bson:"method"`
+	Path         string             `json:"path" bson:"path"`
+	Status       int                `json:"status" bson:"status"`
+	RequestID    string             `json:"request_id" bson:"request_id"`
+	// Changes holds the before and after value of every top level field that changed
+	Changes map[string]Change `json:"changes" bson:"changes"`
+}
+
+// Change is the value of a field before and after a request, null where the field didn't exist
+type Change struct {
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// Key is a field identifying the document a route changes
+type Key struct {
+	// Field is the name of the field in the document
+	Field string
+	// Var is the route variable holding the value
+	Var string
+	// Body is the field of the request body holding the value, when the route has no variable or
+	// the request moves the document. It defaults to Field.
+	Body string
+	// Int values are stored as numbers
+	Int bool
+}
+
+// Target describes the document a route changes, so it can be read before and after the request
+type Target struct {
+	Action       string
+	ResourceType string
+	Collection   string
+	// AfterCollection is where the document is after the request, if it moves. It defaults to
+	// Collection.
+	AfterCollection string
+	Keys            []Key
+}
+
+// redacted are fields whose values are never copied into an event, only the fact they changed
+var redacted = []string{"password"}
+
+// versionPrefix matches the version the API is mounted under, e.g. /api/v1
+var versionPrefix = regexp.MustCompile(`^/api/v[0-9]+`)
+
+// Actor names who made a request: the email in their token or the name of their API key
+func Actor(r *http.Request) string {
+	if name, ok := helper.APIKeyFromContext(r.Context()); ok {
+		return "apikey:" + name
+	}
+	if info, ok := helper.JWTInfoFromContext(r.Context()); ok && info.Email != "" {
+		return info.Email
+	}
+	return "anonymous"
+}
+
+// Record returns middleware that adds an event to the audit log for every successful request to
+// one of the targets, which are keyed by method and route template, e.g. "PUT /courses/{courseShortHand}".
+// It must run after access control so the actor is known.
+func Record(db *mongo.Database, targets map[string]Target) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			target, ok := targets[r.Method+" "+versionPrefix.ReplaceAllString(template, "")]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Keep a copy of the body for the keys of the document, the handler reads it too
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				logger.Error(fmt.Errorf("Error reading the request body: "+err.Error()), http.StatusBadRequest)
+				apierror.WriteInvalidBody(w, r, err, "Error reading the request body.")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			var fields map[string]interface{}
+			json.Unmarshal(body, &fields)
+
+			vars := mux.Vars(r)
+			filter, id := target.filter(vars, fields, false)
+			before := find(db.Collection(target.Collection), filter, id)
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+			if recorder.status >= http.StatusBadRequest {
+				return
+			}
+
+			afterCollection := target.AfterCollection
+			if afterCollection == "" {
+				afterCollection = target.Collection
+			}
+			filter, id = target.filter(vars, fields, true)
+			after := find(db.Collection(afterCollection), filter, id)
+
+			requestID, _ := helper.RequestIDFromContext(r.Context())
+			event := Event{
+				At:           time.Now().UTC(),
+				Actor:        Actor(r),
+				Action:       target.Action,
+				ResourceType: target.ResourceType,
+				ResourceID:   id,
+				Method:       r.Method,
+				Path:         r.URL.Path,
+				Status:       recorder.status,
+				RequestID:    requestID,
+				Changes:      diff(before, after),
+			}
+			// The change is made whether or not it can be recorded, so the response is left as it is
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_, err = db.Collection(Collection).InsertOne(ctx, event)
+			if err != nil {
+				logger.Error(fmt.Errorf("Error recording audit event for "+r.Method+" "+r.URL.Path+": "+err.Error()), http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+// Security - records an event that isn't a change to a resource, such as a lockout. Errors are
+// logged rather than returned, as the request goes on either way.
+func Security(r *http.Request, collection *mongo.Collection, actor string, action string, resourceType string, resourceID string) {
+	requestID, _ := helper.RequestIDFromContext(r.Context())
+	event := Event{
+		At:           time.Now().UTC(),
+		Actor:        actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		RequestID:    requestID,
+		Changes:      map[string]Change{},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.InsertOne(ctx, event)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error recording "+action+" of "+resourceID+": "+err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// Cascade - records a change a request made to a document other than the one its route targets,
+// such as the draft schedules a classroom rename updates. Like Security, errors are only logged.
+func Cascade(r *http.Request, collection *mongo.Collection, action string, resourceType string, resourceID string, before bson.M, after bson.M) {
+	requestID, _ := helper.RequestIDFromContext(r.Context())
+	event := Event{
+		At:           time.Now().UTC(),
+		Actor:        Actor(r),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Status:       http.StatusOK,
+		RequestID:    requestID,
+		Changes:      diff(before, after),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.InsertOne(ctx, event)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error recording "+action+" of "+resourceID+": "+err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// filter builds the filter finding the document and its ID from the route variables and the
+// request body. The body is preferred after the request, as it holds the new keys of a move.
+func (target Target) filter(vars map[string]string, body map[string]interface{}, after bool) (bson.M, string) {
+	filter := bson.M{}
+	var ids []string
+	for _, key := range target.Keys {
+		name := key.Body
+		if name == "" {
+			name = key.Field
+		}
+		text := ""
+		switch value := body[name].(type) {
+		case string:
+			text = strings.TrimSpace(value)
+		case float64:
+			text = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		if routeValue, ok := vars[key.Var]; ok && (!after || text == "") {
+			text = routeValue
+		}
+		if text == "" {
+			return nil, ""
+		}
+		ids = append(ids, text)
+		if key.Int {
+			number, err := strconv.Atoi(text)
+			if err != nil {
+				return nil, ""
+			}
+			filter[key.Field] = number
+		} else {
+			filter[key.Field] = text
+		}
+	}
+	return filter, strings.Join(ids, "/")
+}
+
+// find reads the document matching the filter, nil if there is none
+func find(collection *mongo.Collection, filter bson.M, id string) bson.M {
+	if filter == nil {
+		return nil
+	}
+	var document bson.M
+	err := collection.FindOne(context.TODO(), filter).Decode(&document)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			logger.Error(fmt.Errorf("Error reading "+id+" for the audit log: "+err.Error()), http.StatusInternalServerError)
+		}
+		return nil
+	}
+	return document
+}
+
+// diff lists the top level fields whose values differ between the two documents
+func diff(before bson.M, after bson.M) map[string]Change {
+	changes := map[string]Change{}
+	for field, value := range before {
+		if !reflect.DeepEqual(value, after[field]) {
+			changes[field] = Change{Before: value, After: after[field]}
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = Change{After: value}
+		}
+	}
+	delete(changes, "_id")
+	for _, field := range redacted {
+		if _, ok := changes[field]; ok {
+			changes[field] = Change{Before: "[redacted]", After: "[redacted]"}
+		}
+	}
+	return changes
+}
+
+// statusRecorder remembers the status the handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// eventList - the filters and sort keys of GET /audit
+var eventList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "resource_type", Field: "resource_type", Kind: helper.Exact},
+		{Param: "resource_id", Field: "resource_id", Kind: helper.Exact},
+		{Param: "actor", Field: "actor", Kind: helper.Exact},
+		{Param: "action", Field: "action", Kind: helper.Exact},
+		{Param: "request_id", Field: "request_id", Kind: helper.Exact},
+		{Param: "since", Field: "at", Kind: helper.Since},
+		{Param: "until", Field: "at", Kind: helper.Until},
+	},
+	Sorts:       map[string]string{"at": "at"},
+	DefaultSort: "-at",
+}
+
+// GetEvents - lists the audit events, newest first
+func GetEvents(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetEvents function called.")
+
+	query, err := helper.ParseListQuery(r, eventList)
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	page, err := helper.FindPage[Event](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving audit events: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving audit events.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
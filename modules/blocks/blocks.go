@@ -0,0 +1,298 @@
+package blocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// Constraint strengths. Sections of courses in a hard block must never overlap, overlaps
+// in a soft block are reported but allowed.
+const (
+	Hard = "hard"
+	Soft = "soft"
+)
+
+// Block is a group of courses commonly taken together, such as the required courses of
+// a program year, that should not be scheduled at the same time
+type Block struct {
+	Code       string   `json:"code" bson:"code"`
+	Name       string   `json:"name" bson:"name"`
+	Term       string   `json:"term" bson:"term"`
+	Courses    []string `json:"courses" bson:"courses"`
+	Constraint string   `json:"constraint" bson:"constraint"`
+}
+
+var codeFormat = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Validate checks the block's fields and returns every problem found. An empty term
+// means the block applies to every term, an empty constraint defaults to hard.
+func (b *Block) Validate() []string {
+	var problems []string
+
+	b.Code = strings.ToLower(strings.TrimSpace(b.Code))
+	if !codeFormat.MatchString(b.Code) {
+		problems = append(problems, "code must be a lower case code such as \"seng-3-fall\"")
+	}
+
+	b.Term = strings.ToLower(strings.TrimSpace(b.Term))
+
+	b.Constraint = strings.ToLower(strings.TrimSpace(b.Constraint))
+	if b.Constraint == "" {
+		b.Constraint = Hard
+	}
+	if b.Constraint != Hard && b.Constraint != Soft {
+		problems = append(problems, "constraint must be \""+Hard+"\" or \""+Soft+"\"")
+	}
+
+	seen := map[string]bool{}
+	var courses []string
+	for _, course := range b.Courses {
+		course = strings.ToUpper(strings.TrimSpace(course))
+		if course == "" || seen[course] {
+			continue
+		}
+		seen[course] = true
+		courses = append(courses, course)
+	}
+	b.Courses = courses
+	if len(b.Courses) < 2 {
+		problems = append(problems, "a block needs at least two courses")
+	}
+
+	return problems
+}
+
+// ForTerm returns the blocks that apply to the term, including those without a term
+func ForTerm(ctx context.Context, collection *mongo.Collection, term string) ([]Block, error) {
+	filter := bson.M{"term": bson.M{"$in": bson.A{strings.ToLower(term), ""}}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []Block{}
+	err = cursor.All(ctx, &blocks)
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// missingCourses returns the courses of the block that are not in the courses collection
+func missingCourses(ctx context.Context, courses_coll *mongo.Collection, block Block) ([]string, error) {
+	cursor, err := courses_coll.Find(ctx, bson.M{"shorthand": bson.M{"$in": block.Courses}},
+		options.Find().SetProjection(bson.M{"shorthand": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var found []struct {
+		ShortHand string `bson:"shorthand"`
+	}
+	err = cursor.All(ctx, &found)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := map[string]bool{}
+	for _, course := range found {
+		exists[course.ShortHand] = true
+	}
+	var missing []string
+	for _, course := range block.Courses {
+		if !exists[course] {
+			missing = append(missing, course)
+		}
+	}
+	return missing, nil
+}
+
+// checkBlock validates the block and its courses, writing a bad request response if it is invalid
+func checkBlock(w http.ResponseWriter, r *http.Request, courses_coll *mongo.Collection, block *Block) bool {
+	problems := block.Validate()
+	if len(problems) == 0 {
+		missing, err := missingCourses(r.Context(), courses_coll, *block)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error checking block courses: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking block courses.")
+			return false
+		}
+		for _, course := range missing {
+			problems = append(problems, "course "+course+" doesn't exist")
+		}
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid block: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid block.", apierror.Problems(problems)...)
+		return false
+	}
+	return true
+}
+
+// CreateBlock handles the creation of a new block
+func CreateBlock(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, courses_coll *mongo.Collection) {
+	logger.Info("CreateBlock function called.")
+
+	// Parse request body into Block struct
+	var newBlock Block
+	err := json.NewDecoder(r.Body).Decode(&newBlock)
+	if err != nil {
+		// If there is an error decoding the request body,
+		// log the error and return a bad request response
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	if !checkBlock(w, r, courses_coll, &newBlock) {
+		return
+	}
+
+	// Insert the block, the unique index rejects a duplicate code
+	_, err = collection.InsertOne(context.TODO(), newBlock)
+	if mongo.IsDuplicateKeyError(err) {
+		logger.Error(fmt.Errorf("block %s already exists", newBlock.Code), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Block already exists.")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error inserting block: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error inserting block.")
+		return
+	}
+
+	// Send a response with the created block
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newBlock)
+}
+
+// blockList - the filters and sort keys of GET /blocks, ?term= is handled by GetBlocks
+var blockList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "constraint", Field: "constraint", Kind: helper.Exact},
+		{Param: "course", Field: "courses", Kind: helper.Exact},
+	},
+	Sorts:       map[string]string{"code": "code", "name": "name"},
+	DefaultSort: "code",
+}
+
+// GetBlocks retrieves all blocks, optionally only those that apply to the ?term= query parameter
+func GetBlocks(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetBlocks function called.")
+
+	query, err := helper.ParseListQuery(r, blockList)
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	// Blocks without a term apply to every term
+	if term := r.URL.Query().Get("term"); term != "" {
+		query.Filter["term"] = bson.M{"$in": bson.A{strings.ToLower(term), ""}}
+	}
+
+	page, err := helper.FindPage[Block](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving blocks: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving blocks.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetBlock retrieves a block by code
+func GetBlock(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetBlock function called.")
+
+	var block Block
+	err := collection.FindOne(context.TODO(), blockFilter(r)).Decode(&block)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			logger.Error(fmt.Errorf("block not found"), http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Block not found.")
+		} else {
+			logger.Error(fmt.Errorf("Error getting block: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting block.")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(block)
+}
+
+// UpdateBlock replaces an existing block, the code cannot change
+func UpdateBlock(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, courses_coll *mongo.Collection) {
+	logger.Info("UpdateBlock function called.")
+
+	filter := blockFilter(r)
+
+	var updatedBlock Block
+	err := json.NewDecoder(r.Body).Decode(&updatedBlock)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	// The code comes from the URL
+	updatedBlock.Code = filter["code"].(string)
+
+	if !checkBlock(w, r, courses_coll, &updatedBlock) {
+		return
+	}
+
+	res, err := collection.ReplaceOne(context.TODO(), filter, updatedBlock)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating block: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating block.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		logger.Error(fmt.Errorf("block not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Block not found.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedBlock)
+}
+
+// DeleteBlock handles the deletion of a block
+func DeleteBlock(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("DeleteBlock function called.")
+
+	res, err := collection.DeleteOne(context.TODO(), blockFilter(r))
+	if err != nil {
+		logger.Error(fmt.Errorf("Error deleting block: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error deleting block.")
+		return
+	}
+	if res.DeletedCount == 0 {
+		logger.Error(fmt.Errorf("block not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Block not found.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// blockFilter builds the filter for the block code in the URL
+func blockFilter(r *http.Request) bson.M {
+	return bson.M{"code": strings.ToLower(mux.Vars(r)["code"])}
+}
@@ -0,0 +1,224 @@
+package buildings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// Building describes a campus building, classrooms refer to it by code
+type Building struct {
+	Code     string   `json:"code" bson:"code"`
+	Name     string   `json:"name" bson:"name"`
+	Campus   string   `json:"campus" bson:"campus"`
+	Location Location `json:"location" bson:"location"`
+}
+
+// Location is where a building is on campus
+type Location struct {
+	Address   string  `json:"address" bson:"address"`
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+}
+
+var codeFormat = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+// Validate checks the building's fields and returns every problem found
+func (b *Building) Validate() []string {
+	var problems []string
+
+	b.Code = strings.ToUpper(strings.TrimSpace(b.Code))
+	if !codeFormat.MatchString(b.Code) {
+		problems = append(problems, "code must be letters and digits such as \"ECS\"")
+	}
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if b.Location.Latitude < -90 || b.Location.Latitude > 90 || b.Location.Longitude < -180 || b.Location.Longitude > 180 {
+		problems = append(problems, "location must be a valid latitude and longitude")
+	}
+
+	return problems
+}
+
+// CreateBuilding handles the creation of a new building
+func CreateBuilding(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("CreateBuilding function called.")
+
+	// Parse request body into Building struct
+	var newBuilding Building
+	err := json.NewDecoder(r.Body).Decode(&newBuilding)
+	if err != nil {
+		// If there is an error decoding the request body,
+		// log the error and return a bad request response
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	problems := newBuilding.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid building: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid building.", apierror.Problems(problems)...)
+		return
+	}
+
+	// Insert the building, the unique index rejects a duplicate code
+	_, err = collection.InsertOne(context.TODO(), newBuilding)
+	if mongo.IsDuplicateKeyError(err) {
+		logger.Error(fmt.Errorf("building %s already exists", newBuilding.Code), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Building already exists.")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error inserting building: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error inserting building.")
+		return
+	}
+
+	// Send a response with the created building
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newBuilding)
+}
+
+// buildingList - the filters and sort keys of GET /buildings
+var buildingList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "campus", Field: "campus", Kind: helper.Exact},
+	},
+	Sorts:       map[string]string{"code": "code", "name": "name", "campus": "campus"},
+	DefaultSort: "code",
+}
+
+// GetBuildings retrieves all buildings, optionally only those on the ?campus= query parameter
+func GetBuildings(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetBuildings function called.")
+
+	query, err := helper.ParseListQuery(r, buildingList)
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	page, err := helper.FindPage[Building](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving buildings: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving buildings.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetBuilding retrieves a building by code
+func GetBuilding(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetBuilding function called.")
+
+	var building Building
+	err := collection.FindOne(context.TODO(), buildingFilter(r)).Decode(&building)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			logger.Error(fmt.Errorf("building not found"), http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Building not found.")
+		} else {
+			logger.Error(fmt.Errorf("Error getting building: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting building.")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(building)
+}
+
+// UpdateBuilding replaces an existing building, the code cannot change
+func UpdateBuilding(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("UpdateBuilding function called.")
+
+	filter := buildingFilter(r)
+
+	var updatedBuilding Building
+	err := json.NewDecoder(r.Body).Decode(&updatedBuilding)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	// The code comes from the URL
+	updatedBuilding.Code = filter["code"].(string)
+
+	problems := updatedBuilding.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid building: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid building.", apierror.Problems(problems)...)
+		return
+	}
+
+	res, err := collection.ReplaceOne(context.TODO(), filter, updatedBuilding)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating building: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating building.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		logger.Error(fmt.Errorf("building not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Building not found.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedBuilding)
+}
+
+// DeleteBuilding handles the deletion of a building that has no classrooms left
+func DeleteBuilding(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, classrooms_coll *mongo.Collection) {
+	logger.Info("DeleteBuilding function called.")
+
+	filter := buildingFilter(r)
+
+	count, err := classrooms_coll.CountDocuments(context.TODO(), bson.M{"building": filter["code"]})
+	if err != nil {
+		logger.Error(fmt.Errorf("Error checking classrooms: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking classrooms.")
+		return
+	}
+	if count > 0 {
+		logger.Error(fmt.Errorf("building %s still has %d classrooms", filter["code"], count), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.InUse, fmt.Sprintf("Building still has %d classrooms.", count))
+		return
+	}
+
+	res, err := collection.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error deleting building: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error deleting building.")
+		return
+	}
+	if res.DeletedCount == 0 {
+		logger.Error(fmt.Errorf("building not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Building not found.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// buildingFilter builds the filter for the building code in the URL
+func buildingFilter(r *http.Request) bson.M {
+	return bson.M{"code": strings.ToUpper(mux.Vars(r)["code"])}
+}
@@ -5,18 +5,119 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
 )
 
 type Classroom struct {
-	Building    string `json:"building"`
-	Capacity    int    `json:"capacity"`
-	Room_number string `json:"room" bson:"room"`
+	Building    string     `json:"building"`
+	Capacity    int        `json:"capacity"`
+	Room_number string     `json:"room" bson:"room"`
+	Room_type   string     `json:"room_type" bson:"room_type"`
+	Features    []string   `json:"features" bson:"features"`
+	Blackouts   []Blackout `json:"blackouts" bson:"blackouts"`
+	Version     int64      `json:"version" bson:"version"`
+}
+
+// Blackout is a weekly time block when the classroom can't be booked
+type Blackout struct {
+	Days      []string `json:"days" bson:"days"`
+	StartTime string   `json:"start_time" bson:"start_time"`
+	EndTime   string   `json:"end_time" bson:"end_time"`
+	Reason    string   `json:"reason" bson:"reason"`
+}
+
+// Room types, classrooms without a type are lecture rooms
+const (
+	Lecture = "lecture"
+	Lab     = "lab"
+	Seminar = "seminar"
+)
+
+// RoomTypes lists the valid room types
+var RoomTypes = []string{Lecture, Lab, Seminar}
+
+// weekDays are the day codes used by blackouts, matching the schedule's section days
+var weekDays = []string{"M", "T", "W", "R", "F", "S", "U"}
+
+// Validate checks the classroom's fields, normalizing the room type and features, and returns every problem found
+func (c *Classroom) Validate() []string {
+	var problems []string
+
+	if strings.TrimSpace(c.Building) == "" || strings.TrimSpace(c.Room_number) == "" {
+		problems = append(problems, "building and room are required")
+	}
+	if c.Capacity < 0 {
+		problems = append(problems, "capacity cannot be negative")
+	}
+
+	c.Room_type = strings.ToLower(strings.TrimSpace(c.Room_type))
+	if c.Room_type == "" {
+		c.Room_type = Lecture
+	}
+	if !contains(RoomTypes, c.Room_type) {
+		problems = append(problems, "room_type must be one of "+strings.Join(RoomTypes, ", "))
+	}
+
+	features := []string{}
+	for _, feature := range c.Features {
+		feature = strings.ToLower(strings.TrimSpace(feature))
+		if feature != "" && !contains(features, feature) {
+			features = append(features, feature)
+		}
+	}
+	c.Features = features
+
+	if c.Blackouts == nil {
+		c.Blackouts = []Blackout{}
+	}
+	for _, blackout := range c.Blackouts {
+		if len(blackout.Days) == 0 {
+			problems = append(problems, "a blackout needs at least one day")
+		}
+		for _, day := range blackout.Days {
+			if !contains(weekDays, day) {
+				problems = append(problems, "blackout day "+day+" must be one of "+strings.Join(weekDays, ", "))
+			}
+		}
+		start, startErr := time.Parse("15:04", blackout.StartTime)
+		end, endErr := time.Parse("15:04", blackout.EndTime)
+		if startErr != nil || endErr != nil {
+			problems = append(problems, "blackout times must be written as HH:MM")
+		} else if !start.Before(end) {
+			problems = append(problems, "blackout "+blackout.StartTime+" must start before it ends")
+		}
+	}
+
+	return problems
+}
+
+// RoomType returns the classroom's room type, treating classrooms stored without one as lecture rooms
+func (c Classroom) RoomType() string {
+	if c.Room_type == "" {
+		return Lecture
+	}
+	return c.Room_type
+}
+
+// MissingFeatures returns the required features the classroom doesn't have
+func (c Classroom) MissingFeatures(required []string) []string {
+	var missing []string
+	for _, feature := range required {
+		if !contains(c.Features, feature) {
+			missing = append(missing, feature)
+		}
+	}
+	return missing
 }
 
 // CreateClassroom handles the creation of a new classroom
@@ -30,36 +131,50 @@ func CreateClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.C
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
 		return
 	}
 
-	// Check if shorthand already exists in the collection
-	filter := bson.M{"building": newClassroom.Building}
+	problems := newClassroom.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid classroom: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid classroom.", apierror.Problems(problems)...)
+		return
+	}
+
+	// Check if the room already exists in the building
+	filter := bson.M{"building": newClassroom.Building, "room": newClassroom.Room_number}
 	count, err := collection.CountDocuments(context.TODO(), filter, nil)
 
 	if err != nil {
 		// If there is an error querying the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error checking the collection: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error checking the collection.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking the collection.")
 		return
 	}
 	if count > 0 {
 		// If the count is greater than 0, indicating an existing classroom,
 		// return a conflict response
 		logger.Error(fmt.Errorf("classroom already exists"), http.StatusConflict)
-		http.Error(w, "Classroom already exists.", http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Classroom already exists.")
 		return
 	}
 
 	// Insert the classroom into the MongoDB collection
+	newClassroom.Version = 1
 	_, err = collection.InsertOne(context.TODO(), newClassroom)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the same classroom after the check above
+		logger.Error(fmt.Errorf("classroom already exists"), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Classroom already exists.")
+		return
+	}
 	if err != nil {
 		// If there is an error inserting the classroom into the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error inserting classroom: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving classrooms.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving classrooms.")
 		return
 	}
 
@@ -71,51 +186,38 @@ func CreateClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.C
 	// logger.Info("CreateClassroom function completed.")
 }
 
+// classroomList - the filters and sort keys of GET /classrooms
+var classroomList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "building", Field: "building", Kind: helper.Exact},
+		{Param: "room_type", Field: "room_type", Kind: helper.Exact},
+		{Param: "features", Field: "features", Kind: helper.All},
+		{Param: "min_capacity", Field: "capacity", Kind: helper.Min},
+		{Param: "max_capacity", Field: "capacity", Kind: helper.Max},
+	},
+	Sorts:       map[string]string{"building": "building", "room": "room", "capacity": "capacity"},
+	DefaultSort: "building,room",
+}
+
 func GetClassrooms(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("GetClassrooms function called.")
 
-	// Define an empty slice to store the classrooms
-	var classrooms []Classroom
-
-	// Retrieve all documents from the MongoDB collection
-	cursor, err := collection.Find(context.TODO(), bson.M{})
+	query, err := helper.ParseListQuery(r, classroomList)
 	if err != nil {
-		// If there is an error retrieving classrooms,
-		// log the error and return an internal server error response
-		logger.Error(fmt.Errorf("Error retrieving classrooms: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving classrooms.", http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	// Iterate through the cursor and decode each document into a Classroom struct
-	for cursor.Next(context.TODO()) {
-		var classroom Classroom
-		err := cursor.Decode(&classroom)
-		if err != nil {
-			// If there is an error decoding a classroom document,
-			// log the error and return an internal server error response
-			logger.Error(fmt.Errorf("Error decoding classroom: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error decoding classroom.", http.StatusInternalServerError)
-			return
-		}
-		classrooms = append(classrooms, classroom)
-	}
 
-	// Check for any errors during cursor iteration
-	if err := cursor.Err(); err != nil {
-		// If there is an error iterating through the cursor,
-		// log the error and return an internal server error response
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
+	page, err := helper.FindPage[Classroom](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving classrooms: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving classrooms.")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(classrooms)
-
-	// Uncomment the follow line for debugging
-	// logger.Info("GetClassrooms function completed.")
+	json.NewEncoder(w).Encode(page)
 }
 
 func GetClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
@@ -147,15 +249,21 @@ func GetClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Coll
 			// If the classroom is not found,
 			// log the error and return a not found response
 			logger.Error(fmt.Errorf("Classroom not found: "+err.Error()), http.StatusNotFound)
-			http.Error(w, "Classroom not found.", http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Classroom not found.")
 		} else {
 			// If there is an error retrieving the classroom,
 			// log the error and return an internal server error response
 			logger.Error(fmt.Errorf("Error getting classroom: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error getting classroom.", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting classroom.")
 		}
 		return
 	}
+
+	// The client's copy is still current
+	if etag.NotModified(w, r, classroom.Version) {
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(classroom)
 
@@ -163,9 +271,31 @@ func GetClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Coll
 	// logger.Info("GetClassroom function completed.")
 }
 
+// classroomFields - the fields a merge patch may change, the building and room change through a rename
+var classroomFields = []string{"capacity", "room_type", "features", "blackouts"}
+
+// UpdateClassroom replaces a classroom with the request body, the building and room may be left out
 func UpdateClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("UpdateClassroom function called.")
 
+	updateClassroom(w, r, collection, func(classroom *Classroom) ([]string, error) {
+		*classroom = Classroom{Building: classroom.Building, Room_number: classroom.Room_number}
+		return nil, helper.DecodeStrict(r.Body, classroom)
+	})
+}
+
+// PatchClassroom applies a JSON merge patch from the request body to a classroom
+func PatchClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("PatchClassroom function called.")
+
+	updateClassroom(w, r, collection, func(classroom *Classroom) ([]string, error) {
+		return helper.MergePatch(classroom, r.Body, classroomFields)
+	})
+}
+
+// updateClassroom applies a change from the request body to the stored classroom and saves it
+// once it is valid. The change returns the problems with the body, or an error if it can't be decoded.
+func updateClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, change func(classroom *Classroom) ([]string, error)) {
 	// Parse request params
 	vars := mux.Vars(r)
 	building, ok := vars["building"]
@@ -179,57 +309,87 @@ func UpdateClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.C
 		return
 	}
 
-	// Check if the shorthand exists in the collection
+	// Check if the classroom exists in the collection
 	filter := bson.M{
 		"$and": []bson.M{
 			{"building": building},
 			{"room": room_number},
 		},
 	}
-	exists, err := classroomExists(filter, collection)
+	var updatedClassroom Classroom
+	err := collection.FindOne(context.TODO(), filter).Decode(&updatedClassroom)
+	if err == mongo.ErrNoDocuments {
+		// If the classroom doesn't exist,
+		// return a not found response
+		logger.Error(fmt.Errorf("classroom not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Classroom not found.")
+		return
+	}
 	if err != nil {
 		// If there is an error querying the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error querying collection: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error querying collection.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error querying collection.")
 		return
 	}
-	if !exists {
-		// If the classroom doesn't exist,
-		// return a not found response
-		logger.Error(fmt.Errorf("classroom not found"), http.StatusBadRequest)
-		http.Error(w, "Classroom not found.", http.StatusBadRequest)
+
+	// The client must have read the current version
+	version := updatedClassroom.Version
+	if !etag.CheckIfMatch(w, r, version) {
 		return
 	}
 
-	// Store the filter
-	filter = bson.M{"building": building, "room": room_number}
+	// Store the filter, only updating the version that was checked
+	filter = bson.M{"building": building, "room": room_number, etag.Field: etag.Current(version)}
 
-	// Parse request body into a map
-	var requestBody map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	// Apply the request body to the stored classroom so the result can be validated as a whole
+	problems, err := change(&updatedClassroom)
 	if err != nil {
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.", helper.DecodeProblem(err))
+		return
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid classroom: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid classroom.", apierror.Problems(problems)...)
+		return
+	}
+	updatedClassroom.Version = version + 1
 
+	// The building and room identify the classroom, they can only change through a rename
+	if updatedClassroom.Building != building || updatedClassroom.Room_number != room_number {
+		logger.Error(fmt.Errorf("building and room can't be updated"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Building and room can't be updated, use POST /classrooms/"+building+"/"+room_number+"/rename.")
+		return
+	}
+
+	problems = updatedClassroom.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid classroom: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid classroom.", apierror.Problems(problems)...)
 		return
 	}
 
 	// Construct the update query
-	update := bson.M{"$set": requestBody}
+	update := bson.M{"$set": updatedClassroom}
 
-	_, err = collection.UpdateOne(context.TODO(), filter, update)
+	res, err := collection.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		// If there is an error updating the classroom in the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error updating classroom: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error updating classroom.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating classroom.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		etag.Conflict(w, r)
 		return
 	}
 
 	// Send a response indicating successful classroom update
+	etag.Set(w, updatedClassroom.Version)
 	w.WriteHeader(http.StatusOK)
 	// fmt.Fprintf(w, "Classroom updated successfully")
 
@@ -266,14 +426,14 @@ func DeleteClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.C
 		// If there is an error querying the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error querying collection: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error querying collection.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error querying collection.")
 		return
 	}
 	if !exists {
 		// If the classroom doesn't exist,
 		// return a not found response
-		logger.Error(fmt.Errorf("classroom not found"), http.StatusInternalServerError)
-		http.Error(w, "Classroom not found.", http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("classroom not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Classroom not found.")
 		return
 	}
 
@@ -285,7 +445,7 @@ func DeleteClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.C
 		// If there is an error deleting the classroom from the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error deleting classroom: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error deleting classroom.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error deleting classroom.")
 		return
 	}
 
@@ -297,6 +457,16 @@ func DeleteClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.C
 	// logger.Info("DeleteClassroom function completed.")
 }
 
+// contains checks if the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // classroomExists checks if a document exists in the collection based on a filter
 func classroomExists(filter bson.M, collection *mongo.Collection) (bool, error) {
 	count, err := collection.CountDocuments(context.TODO(), filter, nil)
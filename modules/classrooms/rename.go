@@ -0,0 +1,181 @@
+package classrooms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+)
+
+// RenameRequest is the new building and room of a classroom
+type RenameRequest struct {
+	Building string `json:"building"`
+	Room     string `json:"room"`
+}
+
+// RenameResult reports the renamed classroom and how many draft schedules referenced it
+type RenameResult struct {
+	Classroom      Classroom `json:"classroom"`
+	DraftSchedules int64     `json:"draft_schedules"`
+}
+
+// RenameClassroom moves a classroom to a new building and room, updating the sections of the
+// draft schedules that use it. Approved schedules keep the name the room had at the time. Each
+// draft it changes is recorded in the audit log as well as the classroom.
+func RenameClassroom(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, draft_schedules *mongo.Collection, audit_events *mongo.Collection) {
+	logger.Info("RenameClassroom function called.")
+
+	// Parse request params
+	vars := mux.Vars(r)
+	building := vars["building"]
+	room_number := vars["room"]
+
+	var request RenameRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+	request.Building = strings.TrimSpace(request.Building)
+	request.Room = strings.TrimSpace(request.Room)
+	if request.Building == "" || request.Room == "" {
+		logger.Error(fmt.Errorf("building and room are required"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Building and room are required.")
+		return
+	}
+
+	// Refuse early so nothing changes when the rename can't happen
+	filter := bson.M{"building": building, "room": room_number}
+	count, err := collection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error finding classroom: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error renaming classroom.")
+		return
+	}
+	if count == 0 {
+		logger.Error(fmt.Errorf("classroom not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Classroom not found.")
+		return
+	}
+	if request.Building != building || request.Room != room_number {
+		count, err = collection.CountDocuments(context.TODO(), bson.M{"building": request.Building, "room": request.Room})
+		if err != nil {
+			logger.Error(fmt.Errorf("Error finding classroom: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error renaming classroom.")
+			return
+		}
+		if count > 0 {
+			logger.Error(fmt.Errorf("classroom %s %s already exists", request.Building, request.Room), http.StatusConflict)
+			apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Classroom already exists.")
+			return
+		}
+	}
+
+	// The drafts are updated before the classroom, so if either step fails the classroom keeps
+	// its old name and retrying the rename picks up the drafts that weren't updated yet
+	modified, err := renameInDrafts(r, draft_schedules, audit_events, filter, request)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating draft schedules for renamed classroom: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating the draft schedules, the classroom was not renamed.")
+		return
+	}
+
+	// The unique index still rejects a building and room taken since the check above
+	update := bson.M{"$set": bson.M{"building": request.Building, "room": request.Room}, "$inc": bson.M{etag.Field: 1}}
+	var renamed Classroom
+	err = collection.FindOneAndUpdate(context.TODO(), filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&renamed)
+	if err == mongo.ErrNoDocuments {
+		logger.Error(fmt.Errorf("classroom not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Classroom not found.")
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		logger.Error(fmt.Errorf("classroom %s %s already exists", request.Building, request.Room), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Classroom already exists.")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error renaming classroom: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error renaming classroom.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RenameResult{Classroom: renamed, DraftSchedules: modified})
+}
+
+// renameInDrafts points the draft schedule sections in the old classroom at the new name and
+// records each changed draft in the audit log, returning how many were changed
+func renameInDrafts(r *http.Request, draft_schedules *mongo.Collection, audit_events *mongo.Collection, old bson.M, request RenameRequest) (int64, error) {
+	cursor, err := draft_schedules.Find(context.TODO(), bson.M{"terms.courses.sections": bson.M{"$elemMatch": old}})
+	if err != nil {
+		return 0, err
+	}
+	var before []bson.M
+	err = cursor.All(context.TODO(), &before)
+	if err != nil {
+		return 0, err
+	}
+	if len(before) == 0 {
+		return 0, nil
+	}
+	ids := make([]interface{}, len(before))
+	for i, draft := range before {
+		ids[i] = draft["_id"]
+	}
+
+	res, err := draft_schedules.UpdateMany(context.TODO(),
+		bson.M{"_id": bson.M{"$in": ids}, "terms.courses.sections": bson.M{"$elemMatch": old}},
+		bson.M{"$set": bson.M{
+			"terms.$[].courses.$[].sections.$[section].building": request.Building,
+			"terms.$[].courses.$[].sections.$[section].room":     request.Room,
+		}, "$inc": bson.M{etag.Field: 1}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.M{"section.building": old["building"], "section.room": old["room"]},
+		}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err = draft_schedules.Find(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	var after []bson.M
+	err = cursor.All(context.TODO(), &after)
+	if err != nil {
+		return 0, err
+	}
+	for _, draft := range before {
+		for _, updated := range after {
+			if updated["_id"] == draft["_id"] {
+				audit.Cascade(r, audit_events, "rename_classroom", "schedule", draftID(updated), draft, updated)
+			}
+		}
+	}
+	return res.ModifiedCount, nil
+}
+
+// draftID is the year and term identifying a draft in the audit log, as for the schedule routes
+func draftID(draft bson.M) string {
+	id := fmt.Sprint(draft["year"])
+	if terms, ok := draft["terms"].(bson.A); ok && len(terms) > 0 {
+		if term, ok := terms[0].(bson.M); ok {
+			id += "/" + fmt.Sprint(term["term"])
+		}
+	}
+	return id
+}
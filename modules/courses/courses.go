@@ -8,19 +8,118 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
 )
 
 type Course struct {
-	ShortHand     string     `json:"shorthand" bson:"shorthand"`
-	Course        string     `json:"course" bson:"-"`
-	Name          string     `json:"name" bson:"name"`
-	Prerequisites [][]string `json:"prerequisites" bson:"prerequisites"`
-	CoRequisites  [][]string `json:"corequisites" bson:"corequisites"`
-	TermsOffered  []string   `json:"terms_offered" bson:"terms_offered"`
+	ShortHand        string     `json:"shorthand" bson:"shorthand"`
+	Course           string     `json:"course" bson:"-"`
+	Name             string     `json:"name" bson:"name"`
+	Prerequisites    [][]string `json:"prerequisites" bson:"prerequisites"`
+	CoRequisites     [][]string `json:"corequisites" bson:"corequisites"`
+	TermsOffered     []string   `json:"terms_offered" bson:"terms_offered"`
+	Hours            Hours      `json:"hours" bson:"hours"`
+	Sections         int        `json:"sections" bson:"sections"`
+	MinEnroll        int        `json:"min_enroll" bson:"min_enroll"`
+	MaxEnroll        int        `json:"max_enroll" bson:"max_enroll"`
+	Peng             bool       `json:"peng" bson:"peng"`
+	RequiredFeatures []string   `json:"required_features" bson:"required_features"`
+	RoomType         string     `json:"room_type" bson:"room_type"`
+	Version          int64      `json:"version" bson:"version"`
+}
+
+// Hours - weekly contact hours of a course
+type Hours struct {
+	Lecture  float64 `json:"lecture" bson:"lecture"`
+	Lab      float64 `json:"lab" bson:"lab"`
+	Tutorial float64 `json:"tutorial" bson:"tutorial"`
+}
+
+// Total - returns the total weekly contact hours
+func (h Hours) Total() float64 {
+	return h.Lecture + h.Lab + h.Tutorial
+}
+
+// Array - returns the hours as [lecture, lab, tutorial], the format Algs 1 expects
+func (h Hours) Array() [3]float64 {
+	return [3]float64{h.Lecture, h.Lab, h.Tutorial}
+}
+
+// NewCourse - returns a course with the defaults used for fields a client leaves out
+func NewCourse() Course {
+	return Course{
+		Hours:            Hours{Lecture: 3},
+		Sections:         1,
+		MinEnroll:        5,
+		RequiredFeatures: []string{},
+	}
+}
+
+// ApplyDefaults - fills in the fields that courses stored before they existed are missing
+func (c *Course) ApplyDefaults() {
+	if c.Hours.Total() == 0 {
+		c.Hours = Hours{Lecture: 3}
+	}
+	if c.Sections < 1 {
+		c.Sections = 1
+	}
+	if c.RequiredFeatures == nil {
+		c.RequiredFeatures = []string{}
+	}
+}
+
+// Validate - checks the course fields, normalizing the required features, and returns every problem found
+func (c *Course) Validate() []string {
+	var problems []string
+
+	// CHECK if shorthand is ABC101 format
+	if !hasThreeConsecutiveNumerics(c.ShortHand) {
+		problems = append(problems, "shorthand must contain a three digit course number")
+	}
+	if c.Hours.Lecture < 0 || c.Hours.Lab < 0 || c.Hours.Tutorial < 0 {
+		problems = append(problems, "hours cannot be negative")
+	} else if c.Hours.Total() == 0 {
+		problems = append(problems, "a course needs at least one lecture, lab or tutorial hour")
+	}
+	if c.Sections < 1 {
+		problems = append(problems, "sections must be at least 1")
+	}
+	if c.MinEnroll < 0 {
+		problems = append(problems, "min_enroll cannot be negative")
+	}
+	if c.MaxEnroll < 0 {
+		problems = append(problems, "max_enroll cannot be negative, use 0 for no limit")
+	}
+	if c.MaxEnroll > 0 && c.MinEnroll > c.MaxEnroll {
+		problems = append(problems, "min_enroll cannot be greater than max_enroll")
+	}
+
+	// An empty room type means any classroom will do
+	c.RoomType = strings.ToLower(strings.TrimSpace(c.RoomType))
+	if c.RoomType != "" && !isRoomType(c.RoomType) {
+		problems = append(problems, "room_type must be empty or one of "+strings.Join(classrooms.RoomTypes, ", "))
+	}
+
+	features := []string{}
+	for _, feature := range c.RequiredFeatures {
+		feature = strings.ToLower(strings.TrimSpace(feature))
+		if feature == "" {
+			problems = append(problems, "required_features cannot contain empty names")
+			continue
+		}
+		features = append(features, feature)
+	}
+	c.RequiredFeatures = features
+
+	return problems
 }
 
 // SetCourse - set the course field of Course struct to shorthand
@@ -32,43 +131,66 @@ func (c *Course) SetCourse() {
 func CreateCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("CreateCourse function called.")
 
-	var newCourse Course
+	// Fields left out of the request keep their defaults
+	newCourse := NewCourse()
 	err := json.NewDecoder(r.Body).Decode(&newCourse)
 	if err != nil {
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
 		return
 	}
 
-	// CHECK if shorthand is ABC101 format
-	if !hasThreeConsecutiveNumerics(newCourse.ShortHand) {
-		logger.Error(fmt.Errorf("invalid Course shorthand"), http.StatusBadRequest)
-		http.Error(w, "Invalid Course shorthand.", http.StatusBadRequest)
+	// CHECK if the course fields are valid
+	problems := newCourse.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid course: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid course.", apierror.Problems(problems)...)
+		return
+	}
+
+	// CHECK if the requisites exist and don't form a cycle
+	catalogue, err := loadCatalogue(context.TODO(), collection)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error while retrieving the courses: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while retrieving the courses.")
+		return
+	}
+	problems = checkGraph(catalogue, newCourse, "")
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid course: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid course.", apierror.Problems(problems)...)
 		return
 	}
 
 	// CHECK if course doesn't exist
 	var result Course
-	filter := bson.D{{"shorthand", newCourse.ShortHand}}
+	filter := bson.D{{Key: "shorthand", Value: newCourse.ShortHand}}
 	err = collection.FindOne(context.TODO(), filter).Decode(&result)
 	if err == nil && result.ShortHand != "" {
-		logger.Error(fmt.Errorf("Course %s already exists", result.ShortHand), http.StatusInternalServerError)
-		http.Error(w, fmt.Sprintf("Error: %s course already exists.", result.ShortHand), http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("Course %s already exists", result.ShortHand), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, fmt.Sprintf("Error: %s course already exists.", result.ShortHand))
 		return
 	}
 	if err != nil && err != mongo.ErrNoDocuments {
 		logger.Error(fmt.Errorf("Error while checking for duplicate document: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while checking for duplicate document.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while checking for duplicate document.")
 		return
 	}
 
 	// Insert the user into the MongoDB collection
+	newCourse.Version = 1
 	_, err = collection.InsertOne(context.TODO(), newCourse)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the same course after the check above
+		logger.Error(fmt.Errorf("Course %s already exists", newCourse.ShortHand), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, fmt.Sprintf("Error: %s course already exists.", newCourse.ShortHand))
+		return
+	}
 	if err != nil {
 		logger.Error(fmt.Errorf("Error while inserting course into DB: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while inserting course into DB.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while inserting course into DB.")
 		return
 	}
 
@@ -80,80 +202,86 @@ func CreateCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Coll
 	// logger.Info("CreateCourse function completed.")
 }
 
+// courseList - the filters and sort keys of GET /courses
+var courseList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "shorthand", Field: "shorthand", Kind: helper.Exact},
+		{Param: "terms_offered", Field: "terms_offered", Kind: helper.Exact},
+		{Param: "peng", Field: "peng", Kind: helper.Bool},
+		{Param: "room_type", Field: "room_type", Kind: helper.Exact},
+		{Param: "sections", Field: "sections", Kind: helper.Int},
+		{Param: "required_features", Field: "required_features", Kind: helper.All},
+	},
+	Sorts: map[string]string{
+		"shorthand":  "shorthand",
+		"name":       "name",
+		"sections":   "sections",
+		"min_enroll": "min_enroll",
+		"max_enroll": "max_enroll",
+	},
+	DefaultSort: "shorthand",
+}
+
 // GetCourses - retieves all the courses from the DB
 func GetCourses(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("GetCourses function called.")
 
-	// Define an empty slice to store the users
-	var courses []Course
-
-	// Retrieve all documents from the MongoDB collection
-	cursor, err := collection.Find(context.TODO(), bson.M{})
+	query, err := helper.ParseListQuery(r, courseList)
 	if err != nil {
-		logger.Error(fmt.Errorf("Error retrieving users: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving users.", http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	// Iterate through the cursor and decode each document into a User struct
-	for cursor.Next(context.TODO()) {
-		var course Course
-		err := cursor.Decode(&course)
-		if err != nil {
-			logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-			return
-		}
-		courses = append(courses, course)
-	}
-
-	// Check for any errors during cursor iteration
-	if err := cursor.Err(); err != nil {
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
+	page, err := helper.FindPage[Course](context.TODO(), collection, query, func(course *Course) {
+		course.ApplyDefaults()
+		course.SetCourse()
+	})
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving courses: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving courses.")
 		return
 	}
 
-	// update course field
-	for i := range courses {
-		courses[i].SetCourse()
-	}
-
-	// Send a response with the retrieved users
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(courses)
-
-	// Uncomment the follow line for debugging
-	// logger.Info("GetCourses function completed.")
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetCourse - gets course with the given course shorthand
 func GetCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("GetCourse function called.")
 
-	// Extract the user username from the URL path
-	path := r.URL.Path
-	courseShortHand := strings.TrimPrefix(path, "/courses/")
+	// Extract the course shorthand from the URL path
+	courseShortHand := mux.Vars(r)["courseShortHand"]
 	// CHECK if shorthand is ABC101 format
 	if !hasThreeConsecutiveNumerics(courseShortHand) {
 		logger.Error(fmt.Errorf("invalid Course shorthand"), http.StatusBadRequest)
-		http.Error(w, "Invalid Course shorthand.", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Course shorthand.")
 		return
 	}
 
 	// Get course from DB
 	var result Course
-	filter := bson.D{{"shorthand", courseShortHand}}
+	filter := bson.D{{Key: "shorthand", Value: courseShortHand}}
 	err := collection.FindOne(context.TODO(), filter).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		logger.Error(fmt.Errorf("Course %s doesn't exist", courseShortHand), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, fmt.Sprintf("Error: %s course doesn't exist.", courseShortHand))
+		return
+	}
 	if err != nil {
 		logger.Error(fmt.Errorf("Error while finding the course: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while finding the course.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while finding the course.")
 		return
 	}
 
 	result.SetCourse()
 
+	// The client's copy is still current
+	if etag.NotModified(w, r, result.Version) {
+		return
+	}
+
 	// Send a response
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
@@ -162,64 +290,129 @@ func GetCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collect
 	// logger.Info("GetCourse function completed.")
 }
 
-// UpdateCourse - update the course witht the given shorthand
+// courseFields - the fields a merge patch may change, renaming a course takes a full update
+var courseFields = []string{"name", "prerequisites", "corequisites", "terms_offered", "hours", "sections",
+	"min_enroll", "max_enroll", "peng", "required_features", "room_type"}
+
+// UpdateCourse - replaces the course with the given shorthand, fields left out get their defaults.
+// A different shorthand renames the course.
 func UpdateCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("UpdateCourse function called.")
 
-	// Extract the user username from the URL path
-	path := r.URL.Path
-	courseShortHand := strings.TrimPrefix(path, "/courses/")
+	updateCourse(w, r, collection, func(course *Course) ([]string, error) {
+		shorthand := course.ShortHand
+		*course = NewCourse()
+		course.ShortHand = shorthand
+		return nil, helper.DecodeStrict(r.Body, course)
+	})
+}
 
-	// Check if course exists
-	var result Course
-	filter := bson.D{{"shorthand", courseShortHand}}
-	err := collection.FindOne(context.TODO(), filter).Decode(&result)
+// PatchCourse - applies a JSON merge patch to the course with the given shorthand
+func PatchCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("PatchCourse function called.")
+
+	updateCourse(w, r, collection, func(course *Course) ([]string, error) {
+		return helper.MergePatch(course, r.Body, courseFields)
+	})
+}
+
+// updateCourse - applies a change from the request body to the stored course and saves it once
+// it is valid. The change returns the problems with the body, or an error if it can't be decoded.
+func updateCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, change func(course *Course) ([]string, error)) {
+	// Extract the course shorthand from the URL path
+	courseShortHand := mux.Vars(r)["courseShortHand"]
+
+	// Check if course exists, fields missing from older documents keep their defaults
+	updatedCourse := NewCourse()
+	filter := bson.D{{Key: "shorthand", Value: courseShortHand}}
+	err := collection.FindOne(context.TODO(), filter).Decode(&updatedCourse)
 	if err == mongo.ErrNoDocuments {
-		logger.Error(fmt.Errorf("Course %s doesn't exist", courseShortHand), http.StatusInternalServerError)
-		http.Error(w, fmt.Sprintf("Error: %s course doesn't exist.", courseShortHand), http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("Course %s doesn't exist", courseShortHand), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, fmt.Sprintf("Error: %s course doesn't exist.", courseShortHand))
 		return
 	}
 	if err != nil {
 		logger.Error(fmt.Errorf("Error while finding the course: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while finding the course.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while finding the course.")
 		return
 	}
 
-	// extract body
-	var updateCourse map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updateCourse)
+	// The client must have read the current version
+	version := updatedCourse.Version
+	if !etag.CheckIfMatch(w, r, version) {
+		return
+	}
+
+	// Apply the request body to the stored course so the result can be validated as a whole
+	problems, err := change(&updatedCourse)
 	if err != nil {
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.", helper.DecodeProblem(err))
+		return
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid course: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid course.", apierror.Problems(problems)...)
 		return
 	}
+	updatedCourse.Version = version + 1
 
-	// CHECK if shorthand is ABC101 format
-	if !hasThreeConsecutiveNumerics(courseShortHand) {
-		logger.Error(fmt.Errorf("invalid Course shorthand"), http.StatusBadRequest)
-		http.Error(w, "Invalid Course shorthand.", http.StatusBadRequest)
+	// CHECK if the course fields are valid
+	problems = updatedCourse.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid course: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid course.", apierror.Problems(problems)...)
 		return
 	}
 
-	// delete course field
-	_, ok := updateCourse["course"]
-	if ok {
-		delete(updateCourse, "course")
+	catalogue, err := loadCatalogue(context.TODO(), collection)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error while retrieving the courses: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while retrieving the courses.")
+		return
 	}
 
-	update := bson.M{"$set": updateCourse}
-	// Check if course exists
-	filter = bson.D{{"shorthand", courseShortHand}}
-	_, err = collection.UpdateOne(context.TODO(), filter, update)
+	// Renaming a course would leave the courses that require it pointing at nothing
+	if updatedCourse.ShortHand != courseShortHand {
+		dependents := findDependents(catalogue, courseShortHand)
+		if dependents.hasDependents() {
+			logger.Error(fmt.Errorf("Course %s is required by %s", courseShortHand, strings.Join(dependents.all(), ", ")), http.StatusConflict)
+			apierror.Write(w, r, http.StatusConflict, apierror.InUse, fmt.Sprintf("Error: %s course is required by %s.", courseShortHand, strings.Join(dependents.all(), ", ")))
+			return
+		}
+	}
+
+	// CHECK if the requisites exist and don't form a cycle
+	problems = checkGraph(catalogue, updatedCourse, courseShortHand)
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid course: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid course.", apierror.Problems(problems)...)
+		return
+	}
+
+	// Only update the version that was checked, someone may have changed it since
+	filter = append(filter, bson.E{Key: etag.Field, Value: etag.Current(version)})
+	update := bson.M{"$set": updatedCourse}
+	res, err := collection.UpdateOne(context.TODO(), filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		logger.Error(fmt.Errorf("Course %s already exists", updatedCourse.ShortHand), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, fmt.Sprintf("Error: %s course already exists.", updatedCourse.ShortHand))
+		return
+	}
 	if err != nil {
 		logger.Error(fmt.Errorf("Error while updating the course: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while updating the course.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while updating the course.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		etag.Conflict(w, r)
 		return
 	}
 
 	// Send a response
+	etag.Set(w, updatedCourse.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("Updated Successfuly")
 
@@ -231,29 +424,43 @@ func UpdateCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Coll
 func DeleteCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("DeleteCourse function called.")
 
-	// Extract the user username from the URL path
-	path := r.URL.Path
-	courseShortHand := strings.TrimPrefix(path, "/courses/")
+	// Extract the course shorthand from the URL path
+	courseShortHand := mux.Vars(r)["courseShortHand"]
 
 	// CHECK if shorthand is ABC101 format
 	if !hasThreeConsecutiveNumerics(courseShortHand) {
 		logger.Error(fmt.Errorf("invalid Course shorthand"), http.StatusBadRequest)
-		http.Error(w, "Invalid Course shorthand.", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Course shorthand.")
 		return
 	}
 
 	// Check if course exists
 	var result Course
-	filter := bson.D{{"shorthand", courseShortHand}}
+	filter := bson.D{{Key: "shorthand", Value: courseShortHand}}
 	err := collection.FindOne(context.TODO(), filter).Decode(&result)
 	if err == mongo.ErrNoDocuments {
-		logger.Error(fmt.Errorf("Course %s doesn't exist", result.ShortHand), http.StatusInternalServerError)
-		http.Error(w, fmt.Sprintf("Error: %s course doesn't exist", result.ShortHand), http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("Course %s doesn't exist", courseShortHand), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, fmt.Sprintf("Error: %s course doesn't exist.", courseShortHand))
 		return
 	}
 	if err != nil {
 		logger.Error(fmt.Errorf("Error while finding the course: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while finding the course.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while finding the course.")
+		return
+	}
+
+	// Courses that require this one block the delete unless ?force=true is passed
+	catalogue, err := loadCatalogue(context.TODO(), collection)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error while retrieving the courses: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while retrieving the courses.")
+		return
+	}
+	dependents := findDependents(catalogue, courseShortHand)
+	force := forceRequested(r)
+	if dependents.hasDependents() && !force {
+		logger.Error(fmt.Errorf("Course %s is required by %s", courseShortHand, strings.Join(dependents.all(), ", ")), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.InUse, fmt.Sprintf("Error: %s course is required by %s, use ?force=true to delete it anyway.", courseShortHand, strings.Join(dependents.all(), ", ")))
 		return
 	}
 
@@ -261,10 +468,20 @@ func DeleteCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Coll
 	_, err = collection.DeleteOne(context.TODO(), filter)
 	if err != nil {
 		logger.Error(fmt.Errorf("Error while Deleting the course: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error while Deleting the course.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while Deleting the course.")
 		return
 	}
 
+	// A forced delete also removes the course from the requisites of its dependents
+	if dependents.hasDependents() {
+		err = removeReferences(context.TODO(), collection, courseShortHand)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error while removing references to the course: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while removing references to the course.")
+			return
+		}
+	}
+
 	// Send a response
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("Deleted Successfuly")
@@ -273,6 +490,16 @@ func DeleteCourse(w http.ResponseWriter, r *http.Request, collection *mongo.Coll
 	// logger.Info("DeleteCourse function completed.")
 }
 
+// isRoomType checks if the value is one of the classroom room types
+func isRoomType(value string) bool {
+	for _, roomType := range classrooms.RoomTypes {
+		if value == roomType {
+			return true
+		}
+	}
+	return false
+}
+
 // checks for three consecutive digits
 func hasThreeConsecutiveNumerics(input string) bool {
 	re := regexp.MustCompile(`\d{3}`)
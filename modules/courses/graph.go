@@ -0,0 +1,332 @@
+package courses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+)
+
+// PrerequisiteNode - one course in a prerequisite tree. The groups of prerequisites are
+// alternatives, any one of them is enough, and every course of a group is required. The
+// seed data writes CSC115 or CSC116 with MATH122 as [["CSC115","MATH122"],["CSC116","MATH122"]].
+type PrerequisiteNode struct {
+	Course        string               `json:"course"`
+	Name          string               `json:"name"`
+	Prerequisites [][]PrerequisiteNode `json:"prerequisites"`
+	CoRequisites  [][]string           `json:"corequisites"`
+	Missing       bool                 `json:"missing,omitempty"`
+	Cycle         bool                 `json:"cycle,omitempty"`
+}
+
+// Dependents - the courses that require a course, directly or through other courses
+type Dependents struct {
+	Course         string   `json:"course"`
+	PrerequisiteOf []string `json:"prerequisite_of"`
+	CorequisiteOf  []string `json:"corequisite_of"`
+	Transitive     []string `json:"transitive"`
+}
+
+// loadCatalogue - reads every course into a map keyed by shorthand
+func loadCatalogue(ctx context.Context, collection *mongo.Collection) (map[string]Course, error) {
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var list []Course
+	err = cursor.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogue := make(map[string]Course, len(list))
+	for _, course := range list {
+		catalogue[course.ShortHand] = course
+	}
+	return catalogue, nil
+}
+
+// requisites - flattens the groups of a prerequisite or corequisite list, the seed data
+// uses [[""]] for a course without requisites so empty names are skipped
+func requisites(groups [][]string) []string {
+	var names []string
+	for _, group := range groups {
+		for _, name := range group {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// checkGraph - checks a new or updated course against the rest of the catalogue. Every
+// course it newly references must exist and the prerequisites must not form a cycle.
+// previous is the shorthand the course had before the update, or "" when it is being
+// created. References the course already had are left alone even if they point at nothing,
+// as some in the seed data do, so updates that don't touch them still go through.
+func checkGraph(catalogue map[string]Course, course Course, previous string) []string {
+	var problems []string
+
+	known := map[string]bool{}
+	if previous != "" {
+		stored := catalogue[previous]
+		for _, name := range append(requisites(stored.Prerequisites), requisites(stored.CoRequisites)...) {
+			known[name] = true
+		}
+		delete(catalogue, previous)
+	}
+	catalogue[course.ShortHand] = course
+
+	for _, name := range requisites(course.Prerequisites) {
+		if _, ok := catalogue[name]; !ok && !known[name] {
+			problems = append(problems, fmt.Sprintf("prerequisite %s doesn't exist", name))
+		}
+	}
+	for _, name := range requisites(course.CoRequisites) {
+		if _, ok := catalogue[name]; !ok && !known[name] {
+			problems = append(problems, fmt.Sprintf("corequisite %s doesn't exist", name))
+		}
+	}
+
+	if cycle := findCycle(catalogue); cycle != nil {
+		problems = append(problems, "prerequisite cycle "+strings.Join(cycle, " -> "))
+	}
+	return problems
+}
+
+// findCycle - returns the first prerequisite cycle found in the catalogue, or nil.
+// Corequisites are taken together, so they are allowed to reference each other.
+func findCycle(catalogue map[string]Course) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(catalogue))
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, next := range requisites(catalogue[name].Prerequisites) {
+			if _, ok := catalogue[next]; !ok {
+				continue
+			}
+			switch state[next] {
+			case visiting:
+				// Report the cycle from where it starts
+				for i := range path {
+					if path[i] == next {
+						return append(append([]string{}, path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+
+	// Sort so the same catalogue always reports the same cycle
+	names := make([]string, 0, len(catalogue))
+	for name := range catalogue {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// findDependents - returns the courses that require the given course, directly as a
+// prerequisite or corequisite and transitively through prerequisites
+func findDependents(catalogue map[string]Course, shorthand string) Dependents {
+	dependents := Dependents{
+		Course:         shorthand,
+		PrerequisiteOf: []string{},
+		CorequisiteOf:  []string{},
+		Transitive:     []string{},
+	}
+
+	// Reverse the prerequisite edges once
+	requiredBy := make(map[string][]string)
+	for name, course := range catalogue {
+		for _, prerequisite := range requisites(course.Prerequisites) {
+			requiredBy[prerequisite] = append(requiredBy[prerequisite], name)
+		}
+		for _, corequisite := range requisites(course.CoRequisites) {
+			if corequisite == shorthand {
+				dependents.CorequisiteOf = append(dependents.CorequisiteOf, name)
+			}
+		}
+	}
+	dependents.PrerequisiteOf = append(dependents.PrerequisiteOf, requiredBy[shorthand]...)
+
+	seen := map[string]bool{shorthand: true}
+	queue := append([]string{}, requiredBy[shorthand]...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		dependents.Transitive = append(dependents.Transitive, name)
+		queue = append(queue, requiredBy[name]...)
+	}
+
+	sort.Strings(dependents.PrerequisiteOf)
+	sort.Strings(dependents.CorequisiteOf)
+	sort.Strings(dependents.Transitive)
+	return dependents
+}
+
+// hasDependents - reports whether any other course references the given course
+func (d Dependents) hasDependents() bool {
+	return len(d.PrerequisiteOf) > 0 || len(d.CorequisiteOf) > 0
+}
+
+// all - returns the courses that reference the given course directly
+func (d Dependents) all() []string {
+	names := append(append([]string{}, d.PrerequisiteOf...), d.CorequisiteOf...)
+	sort.Strings(names)
+	return names
+}
+
+// buildTree - builds the transitive prerequisite tree of a course. Courses already on
+// the current path are marked as a cycle instead of being expanded again.
+func buildTree(catalogue map[string]Course, shorthand string, path map[string]bool) PrerequisiteNode {
+	course, ok := catalogue[shorthand]
+	node := PrerequisiteNode{Course: shorthand, Prerequisites: [][]PrerequisiteNode{}, CoRequisites: [][]string{}}
+	if !ok {
+		node.Missing = true
+		return node
+	}
+	node.Name = course.Name
+	if path[shorthand] {
+		node.Cycle = true
+		return node
+	}
+
+	path[shorthand] = true
+	for _, group := range course.Prerequisites {
+		var required []PrerequisiteNode
+		for _, name := range group {
+			if name != "" {
+				required = append(required, buildTree(catalogue, name, path))
+			}
+		}
+		if len(required) > 0 {
+			node.Prerequisites = append(node.Prerequisites, required)
+		}
+	}
+	delete(path, shorthand)
+
+	for _, group := range course.CoRequisites {
+		var required []string
+		for _, name := range group {
+			if name != "" {
+				required = append(required, name)
+			}
+		}
+		if len(required) > 0 {
+			node.CoRequisites = append(node.CoRequisites, required)
+		}
+	}
+	return node
+}
+
+// removeReferences - removes a deleted course from the requisites of every other course.
+// Each inner list is all required, so an alternative that needed the course is dropped as a
+// whole rather than left requiring the rest of its courses.
+func removeReferences(ctx context.Context, collection *mongo.Collection, shorthand string) error {
+	for _, field := range []string{"prerequisites", "corequisites"} {
+		alternative := bson.M{"$elemMatch": bson.M{"$eq": shorthand}}
+		_, err := collection.UpdateMany(ctx,
+			bson.M{field: bson.M{"$elemMatch": alternative}},
+			bson.M{"$pull": bson.M{field: alternative}, "$inc": bson.M{etag.Field: 1}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPrerequisites - returns the transitive prerequisite tree of a course
+func GetPrerequisites(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetPrerequisites function called.")
+
+	courseShortHand := mux.Vars(r)["courseShortHand"]
+
+	catalogue, err := loadCatalogue(r.Context(), collection)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error while retrieving the courses: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while retrieving the courses.")
+		return
+	}
+	if _, ok := catalogue[courseShortHand]; !ok {
+		logger.Error(fmt.Errorf("Course %s doesn't exist", courseShortHand), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, fmt.Sprintf("Error: %s course doesn't exist.", courseShortHand))
+		return
+	}
+
+	tree := buildTree(catalogue, courseShortHand, map[string]bool{})
+
+	// Send a response
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tree)
+}
+
+// GetDependents - returns the courses that require a course
+func GetDependents(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetDependents function called.")
+
+	courseShortHand := mux.Vars(r)["courseShortHand"]
+
+	catalogue, err := loadCatalogue(r.Context(), collection)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error while retrieving the courses: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while retrieving the courses.")
+		return
+	}
+	if _, ok := catalogue[courseShortHand]; !ok {
+		logger.Error(fmt.Errorf("Course %s doesn't exist", courseShortHand), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, fmt.Sprintf("Error: %s course doesn't exist.", courseShortHand))
+		return
+	}
+
+	dependents := findDependents(catalogue, courseShortHand)
+
+	// Send a response
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dependents)
+}
+
+// forceRequested - reports whether the request asked to go ahead despite dependents
+func forceRequested(r *http.Request) bool {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	return force
+}
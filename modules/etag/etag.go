@@ -0,0 +1,89 @@
+package etag
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+)
+
+// Field is the version of users, courses, classrooms and schedules. Every write increments it,
+// and its entity tag lets a client tell whether the document changed since it was read.
+const Field = "version"
+
+// Tag - the entity tag of a version
+func Tag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// Set - sends the entity tag of the version in the ETag header
+func Set(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", Tag(version))
+}
+
+// Current - filter value matching a document only while it is still at the version, documents
+// stored before versions were added have none
+func Current(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// NotModified - sets the ETag header and reports whether the If-None-Match header names the
+// version, in which case it has answered 304 and the caller must not write a body
+func NotModified(w http.ResponseWriter, r *http.Request, version int64) bool {
+	Set(w, version)
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matches(header, version, true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// CheckIfMatch - reports whether the If-Match header names the stored version. If it is
+// missing it answers 428, if it names another version 412.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, version int64) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		logger.Error(fmt.Errorf("If-Match header missing"), http.StatusPreconditionRequired)
+		apierror.Write(w, r, http.StatusPreconditionRequired, apierror.PreconditionNeeded, "Send the ETag of the resource in the If-Match header.")
+		return false
+	}
+	if !matches(header, version, false) {
+		Conflict(w, r)
+		return false
+	}
+	return true
+}
+
+// Conflict - answers 412 because the resource is no longer at the version the client read
+func Conflict(w http.ResponseWriter, r *http.Request) {
+	logger.Error(fmt.Errorf("resource changed since the client read it"), http.StatusPreconditionFailed)
+	apierror.Write(w, r, http.StatusPreconditionFailed, apierror.PreconditionFailed, "The resource was changed by someone else, fetch it again.")
+}
+
+// matches checks a list of entity tags against the version. If-None-Match compares weakly,
+// If-Match strongly, so W/ tags never match it.
+func matches(header string, version int64, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	tag := Tag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
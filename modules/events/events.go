@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Types of the events published when a schedule or a user changes
+const (
+	ScheduleGenerated   = "schedule_generated"
+	SectionReassigned   = "section_reassigned"
+	ScheduleApproved    = "schedule_approved"
+	PreferencesApproved = "preferences_approved"
+	PreferencesRejected = "preferences_rejected"
+)
+
+// Types lists every event type, in the order they are documented
+var Types = []string{ScheduleGenerated, SectionReassigned, ScheduleApproved, PreferencesApproved, PreferencesRejected}
+
+// Event is something that happened that users may want to hear about. Only the fields of its
+// type are set.
+type Event struct {
+	Type string `json:"type" bson:"type"`
+	Year int    `json:"year,omitempty" bson:"year,omitempty"`
+	Term string `json:"term,omitempty" bson:"term,omitempty"`
+	// Course and Section name a reassigned section
+	Course  string `json:"course,omitempty" bson:"course,omitempty"`
+	Section string `json:"section,omitempty" bson:"section,omitempty"`
+	// From and To are the professors of a reassigned section before and after, empty if it is new
+	// or was removed
+	From string `json:"from,omitempty" bson:"from,omitempty"`
+	To   string `json:"to,omitempty" bson:"to,omitempty"`
+	// Professors are the professors a schedule event concerns, named as the schedule names them
+	Professors []string `json:"-" bson:"-"`
+	// Username is the user a user event concerns
+	Username string `json:"username,omitempty" bson:"username,omitempty"`
+}
+
+// Handler is told about every event published
+type Handler func(ctx context.Context, event Event)
+
+var (
+	mu       sync.RWMutex
+	handlers []Handler
+)
+
+// Subscribe - calls the handler with every event published from now on
+func Subscribe(handler Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Publish - tells every subscriber about the event. Subscribers are called in turn before it
+// returns, so they should only record the event and do any slow work later.
+func Publish(ctx context.Context, event Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
@@ -3,13 +3,23 @@ package helper
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"os"
 )
 
-func VerifyAPIKey(keyPlainText string) bool {
+// VerifyAPIKey - checks the plain text key against the configured SHA-256 hash
+func VerifyAPIKey(keyPlainText string, apiHash string) bool {
 	hash := sha256.Sum256([]byte(keyPlainText))
-	if hex.EncodeToString(hash[:]) == os.Getenv("API_HASH") {
+	if hex.EncodeToString(hash[:]) == apiHash {
 		return true
 	}
 	return false
 }
+
+// FindAPIKey - returns the name of the key whose hash matches the plain text key
+func FindAPIKey(keyPlainText string, hashes map[string]string) (string, bool) {
+	for name, hash := range hashes {
+		if VerifyAPIKey(keyPlainText, hash) {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,23 @@
+package helper
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP - returns the address of the client. Behind a trusted proxy it is the last address in
+// X-Forwarded-For, the one the proxy added, as the client can forge the ones before it.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addresses := strings.Split(forwarded, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,47 @@
+package helper
+
+import "context"
+
+// jwtInfoKey is the context key the middleware stores the verified caller under
+type jwtInfoKey struct{}
+
+// WithJWTInfo - returns a copy of the context carrying the verified caller of the request
+func WithJWTInfo(ctx context.Context, info JWT_INFO) context.Context {
+	return context.WithValue(ctx, jwtInfoKey{}, info)
+}
+
+// JWTInfoFromContext - returns the caller verified by the middleware, ok is false when the
+// request didn't go through it
+func JWTInfoFromContext(ctx context.Context) (JWT_INFO, bool) {
+	info, ok := ctx.Value(jwtInfoKey{}).(JWT_INFO)
+	return info, ok
+}
+
+// requestIDKey is the context key the middleware stores the request ID under
+type requestIDKey struct{}
+
+// WithRequestID - returns a copy of the context carrying the ID of the request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext - returns the ID the middleware gave the request
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// apiKeyKey is the context key the middleware stores the name of the API key used under
+type apiKeyKey struct{}
+
+// WithAPIKey - returns a copy of the context carrying the name of the API key the caller used
+func WithAPIKey(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, name)
+}
+
+// APIKeyFromContext - returns the name of the API key the caller used, ok is false for callers
+// with a token
+func APIKeyFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(apiKeyKey{}).(string)
+	return name, ok
+}
@@ -2,7 +2,6 @@ package helper
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -16,13 +15,13 @@ type JWT_INFO struct {
 }
 
 // VerifyJWT - decrypts JWT to make sure its valid and also checks whether it is past its expiry or not
-func VerifyJWT(tokenString string) (bool, JWT_INFO, error) {
+func VerifyJWT(tokenString string, secret string) (bool, JWT_INFO, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
         _, ok := token.Method.(*jwt.SigningMethodHMAC)
         if !ok {
             return nil, fmt.Errorf("Invalid Token")
         }
-        return []byte(os.Getenv("JWT_SECRET")), nil
+        return []byte(secret), nil
     }
 
 	// parse claims
@@ -0,0 +1,267 @@
+package helper
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Page sizes used when the request doesn't give a limit, and the most a request can ask for
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// FilterKind - how a query parameter is turned into a MongoDB condition
+type FilterKind int
+
+const (
+	// Exact matches any of the comma separated values, or an element of an array field
+	Exact FilterKind = iota
+	// All matches array fields holding every one of the comma separated values
+	All
+	// Bool matches a true or false value
+	Bool
+	// Int matches a whole number
+	Int
+	// Min matches numbers greater than or equal to the value
+	Min
+	// Max matches numbers less than or equal to the value
+	Max
+	// Since matches times at or after the RFC 3339 value
+	Since
+	// Until matches times before the RFC 3339 value
+	Until
+)
+
+// Filter - a query parameter a list endpoint accepts and the document field it filters on
+type Filter struct {
+	Param string
+	Field string
+	Kind  FilterKind
+}
+
+// ListSpec - the filters and sort keys a list endpoint accepts. Sorts maps the name used
+// in ?sort= to the document field, DefaultSort is used when the request has no ?sort=.
+type ListSpec struct {
+	Filters     []Filter
+	Sorts       map[string]string
+	DefaultSort string
+}
+
+// ListQuery - the parsed pagination, filter, sort and fields parameters of a list request
+type ListQuery struct {
+	Filter bson.M
+	Sort   bson.D
+	Fields []string
+	Limit  int64
+	Offset int64
+}
+
+// Page - the envelope every list endpoint responds with. NextCursor is empty on the last page.
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+	Total      int64       `json:"total"`
+}
+
+// cursor is the position encoded in the opaque next_cursor value
+type cursor struct {
+	Offset int64 `json:"offset"`
+}
+
+// ParseListQuery - reads ?limit=, ?cursor=, ?sort=, ?fields= and the filters of the spec
+// from the request. Query parameters the spec doesn't know are ignored.
+func ParseListQuery(r *http.Request, spec ListSpec) (ListQuery, error) {
+	values := r.URL.Query()
+	query := ListQuery{Filter: bson.M{}, Limit: DefaultLimit}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		query.Limit = limit
+	}
+
+	if value := values.Get("cursor"); value != "" {
+		var position cursor
+		raw, err := base64.RawURLEncoding.DecodeString(value)
+		if err == nil {
+			err = json.Unmarshal(raw, &position)
+		}
+		if err != nil || position.Offset < 0 {
+			return query, fmt.Errorf("invalid cursor")
+		}
+		query.Offset = position.Offset
+	}
+
+	for _, filter := range spec.Filters {
+		value := strings.TrimSpace(values.Get(filter.Param))
+		if value == "" {
+			continue
+		}
+		condition, err := filterCondition(filter, value)
+		if err != nil {
+			return query, err
+		}
+		// Two filters on the same field, such as a minimum and a maximum, are combined
+		if existing, ok := query.Filter[filter.Field].(bson.M); ok {
+			if extra, ok := condition.(bson.M); ok {
+				for operator, operand := range extra {
+					existing[operator] = operand
+				}
+				continue
+			}
+		}
+		query.Filter[filter.Field] = condition
+	}
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = spec.DefaultSort
+	}
+	for _, key := range splitList(sort) {
+		direction := 1
+		if strings.HasPrefix(key, "-") {
+			direction = -1
+			key = key[1:]
+		}
+		field, ok := spec.Sorts[key]
+		if !ok {
+			return query, fmt.Errorf("can't sort by %q", key)
+		}
+		query.Sort = append(query.Sort, bson.E{Key: field, Value: direction})
+	}
+	// Break ties so pages don't overlap
+	query.Sort = append(query.Sort, bson.E{Key: "_id", Value: 1})
+
+	query.Fields = splitList(values.Get("fields"))
+
+	return query, nil
+}
+
+// filterCondition builds the MongoDB condition for one filter value
+func filterCondition(filter Filter, value string) (interface{}, error) {
+	switch filter.Kind {
+	case Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", filter.Param)
+		}
+		return parsed, nil
+	case Int, Min, Max:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number", filter.Param)
+		}
+		switch filter.Kind {
+		case Min:
+			return bson.M{"$gte": parsed}, nil
+		case Max:
+			return bson.M{"$lte": parsed}, nil
+		}
+		return parsed, nil
+	case All:
+		return bson.M{"$all": splitList(value)}, nil
+	case Since, Until:
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 time such as 2024-09-01T00:00:00Z", filter.Param)
+		}
+		if filter.Kind == Since {
+			return bson.M{"$gte": parsed}, nil
+		}
+		return bson.M{"$lt": parsed}, nil
+	}
+
+	list := splitList(value)
+	if len(list) == 1 {
+		return list[0], nil
+	}
+	return bson.M{"$in": list}, nil
+}
+
+// FindPage - runs the list query against the collection and builds the response page.
+// prepare, if given, is called on every item before it is returned.
+func FindPage[T any](ctx context.Context, collection *mongo.Collection, query ListQuery, prepare func(*T)) (Page, error) {
+	total, err := collection.CountDocuments(ctx, query.Filter)
+	if err != nil {
+		return Page{}, err
+	}
+
+	opts := options.Find().SetSort(query.Sort).SetSkip(query.Offset).SetLimit(query.Limit)
+	found, err := collection.Find(ctx, query.Filter, opts)
+	if err != nil {
+		return Page{}, err
+	}
+	items := []T{}
+	err = found.All(ctx, &items)
+	if err != nil {
+		return Page{}, err
+	}
+	if prepare != nil {
+		for i := range items {
+			prepare(&items[i])
+		}
+	}
+
+	page := Page{Items: items, Total: total}
+	if next := query.Offset + int64(len(items)); len(items) > 0 && next < total {
+		raw, _ := json.Marshal(cursor{Offset: next})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	if len(query.Fields) > 0 {
+		page.Items, err = sparseFields(items, query.Fields)
+		if err != nil {
+			return Page{}, err
+		}
+	}
+	return page, nil
+}
+
+// sparseFields keeps only the requested JSON fields of every item
+func sparseFields(items interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var full []map[string]json.RawMessage
+	err = json.Unmarshal(raw, &full)
+	if err != nil {
+		return nil, err
+	}
+
+	sparse := make([]map[string]json.RawMessage, len(full))
+	for i, item := range full {
+		sparse[i] = map[string]json.RawMessage{}
+		for _, field := range fields {
+			if value, ok := item[field]; ok {
+				sparse[i][field] = value
+			}
+		}
+	}
+	return sparse, nil
+}
+
+// splitList splits a comma separated query value, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
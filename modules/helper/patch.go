@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DecodeStrict - decodes a JSON body into target, rejecting fields the type doesn't have
+func DecodeStrict(body io.Reader, target interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// DecodeProblem - describes a decoding error in terms of the JSON the client sent
+func DecodeProblem(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Sprintf("%s must be %s", typeErr.Field, jsonKind(typeErr.Type))
+	}
+	return strings.TrimPrefix(err.Error(), "json: ")
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// MergePatch - applies an RFC 7396 JSON merge patch from the body to the resource target points
+// to. The patch may only name the allowed top level fields, any other is returned as a problem.
+// The result is decoded back into target strictly, so a value of the wrong type is a problem
+// too. Fields that aren't part of the JSON form of the resource keep their values. The error is
+// only set if the body isn't a JSON object.
+func MergePatch(target interface{}, body io.Reader, allowed []string) ([]string, error) {
+	var patch map[string]interface{}
+	err := json.NewDecoder(body).Decode(&patch)
+	if err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		return nil, errors.New("a merge patch must be a JSON object")
+	}
+
+	var problems []string
+	for field := range patch {
+		if !containsString(allowed, field) {
+			problems = append(problems, field+" can't be changed")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return problems, nil
+	}
+
+	current, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	err = json.Unmarshal(current, &document)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := json.Marshal(mergeValue(document, patch))
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields the patch removed must end up empty rather than keep their old values
+	clearJSONFields(target)
+	err = DecodeStrict(bytes.NewReader(patched), target)
+	if err != nil {
+		return []string{DecodeProblem(err)}, nil
+	}
+	return nil, nil
+}
+
+// mergeValue merges a patch into a JSON value the way RFC 7396 describes: objects are merged
+// field by field, null removes a field and anything else replaces the value
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = mergeValue(object[name], value)
+	}
+	return object
+}
+
+// clearJSONFields zeroes the fields of the struct target points to that have a JSON form
+func clearJSONFields(target interface{}) {
+	value := reflect.ValueOf(target).Elem()
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		value.Field(i).Set(reflect.Zero(field.Type))
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
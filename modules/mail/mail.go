@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"embed"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+)
+
+// Message is an email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email. Implementations must be safe to use from several requests at once.
+type Mailer interface {
+	Send(message Message) error
+}
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templates holds every message, each file defines a "subject" and a "body" template
+var templates = map[string]*template.Template{}
+
+func init() {
+	files, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".tmpl")
+		templates[name] = template.Must(template.ParseFS(templateFiles, "templates/"+file.Name()))
+	}
+}
+
+// Compose renders the message template with the given name, e.g. "password_reset", for the
+// recipient
+func Compose(to string, name string, data interface{}) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("no mail template named %q", name)
+	}
+	var subject, body bytes.Buffer
+	err := tmpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+	err = tmpl.ExecuteTemplate(&body, "body", data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: strings.TrimSpace(subject.String()), Body: strings.TrimSpace(body.String()) + "\n"}, nil
+}
+
+// format writes the message as an RFC 5322 email from the sender
+func (message Message) format(from string) []byte {
+	var email bytes.Buffer
+	email.WriteString("From: " + from + "\r\n")
+	email.WriteString("To: " + message.To + "\r\n")
+	email.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	email.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	email.WriteString("MIME-Version: 1.0\r\n")
+	email.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	email.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return email.Bytes()
+}
+
+// SMTPMailer sends email through an SMTP server, upgrading to TLS when the server offers it
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// smtpTimeout bounds how long sending one message may take, as the request waits for it
+const smtpTimeout = 30 * time.Second
+
+func (m SMTPMailer) Send(message Message) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)), smtpTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: m.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(m.From)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(message.To)
+	if err != nil {
+		return err
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = data.Write(message.format(m.From))
+	if err != nil {
+		return err
+	}
+	err = data.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// unsafeFileName matches the characters replaced in the names of the files FileMailer writes
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9@._-]`)
+
+// FileMailer writes every message to a .eml file in Dir instead of sending it, for development
+// and tests
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(message Message) error {
+	err := os.MkdirAll(m.Dir, 0700)
+	if err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + unsafeFileName.ReplaceAllString(message.To, "_") + ".eml"
+	return os.WriteFile(filepath.Join(m.Dir, name), message.format(m.From), 0600)
+}
+
+// LogMailer logs every message instead of sending it, for development. Messages such as
+// password resets hold secrets, so it must not be used in production.
+type LogMailer struct{}
+
+func (LogMailer) Send(message Message) error {
+	logger.Info("Mail to " + message.To + ": " + message.Subject + "\n" + message.Body)
+	return nil
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecation describes routes that are still served but will be removed
+type Deprecation struct {
+	// Since is when the routes were deprecated
+	Since time.Time
+	// Sunset is when the routes stop being served
+	Sunset time.Time
+	// Successor maps the path of a request to the path replacing it, no Link header is sent if nil
+	Successor func(path string) string
+}
+
+// Deprecated marks every response of the routes it wraps with the Deprecation (RFC 9745) and
+// Sunset (RFC 8594) headers, and a successor-version link when there is one
+func Deprecated(d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+			w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			if d.Successor != nil {
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor(r.URL.Path)))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -4,18 +4,21 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
+	"github.com/SENG-499-Company2-B01/Backend/config"
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
 	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
 	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func fetch_email(path string, collection *mongo.Collection) string {
+func fetch_email(username string, collection *mongo.Collection) string {
 
-	username := strings.TrimPrefix(path, "/users/")
 	username = strings.TrimSpace(username)
 
 	filter := bson.M{"username": username}
@@ -44,17 +47,46 @@ func valid_permissions(r *http.Request, isAdmin bool, jwt_email string, collecti
 			return true
 		}
 		// If we have a non-admin token, a user can only update themselves
-		return jwt_email == fetch_email(r.URL.Path, collection)
+		return jwt_email == fetch_email(mux.Vars(r)["username"], collection)
 	}
 
 	return true
 }
 
+// active_caller returns the active user the token was issued to. Tokens outlive changes to the
+// account, so the user is looked up on every request rather than trusted from the claims.
+func active_caller(ctx context.Context, email string, collection *mongo.Collection) (users.User, error) {
+	var user users.User
+	err := collection.FindOne(ctx, bson.M{"email": email, "deactivated_at": nil}).Decode(&user)
+	return user, err
+}
+
+// versionPrefix matches the version the API is mounted under, e.g. /api/v1
+var versionPrefix = regexp.MustCompile(`^/api/v[0-9]+`)
+
+// resourcePath is the path of the request without the API version, so the legacy and versioned
+// routes are checked the same way
+func resourcePath(r *http.Request) string {
+	return versionPrefix.ReplaceAllString(r.URL.Path, "")
+}
+
+// adminOnlyUserRoute reports whether the path is a users route only admins may call, whatever
+// the method: reactivation, unlocking, admin rights and the deactivated users report
+func adminOnlyUserRoute(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "users" {
+		return false
+	}
+	return parts[2] == "reactivate" || parts[2] == "unlock" || parts[2] == "admin" || parts[1] == "deactivated"
+}
+
 // Middleware function, which will be called for each request
-func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) http.Handler {
+func Users_API_Access_Control(next http.Handler, collection *mongo.Collection, cfg *config.Config) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// ignore if this is not a call to users or prev schedules, classrooms
-		if !strings.Contains(r.URL.Path, "/users") && !strings.Contains(r.URL.Path, "/courses") && !strings.Contains(r.URL.Path, "/schedules/prev") && !strings.Contains(r.URL.Path, "/schedules") && !strings.Contains(r.URL.Path, "/classrooms") {
+		path := resourcePath(r)
+
+		// ignore if this is not a call to users or prev schedules, classrooms, terms, blocks, buildings, search, audit
+		if !strings.Contains(path, "/audit") && !strings.Contains(path, "/users") && !strings.Contains(path, "/courses") && !strings.Contains(path, "/schedules/prev") && !strings.Contains(path, "/schedules") && !strings.Contains(path, "/classrooms") && !strings.Contains(path, "/terms") && !strings.Contains(path, "/blocks") && !strings.Contains(path, "/buildings") && !strings.Contains(path, "/search") {
 			// Middleware successful
 			next.ServeHTTP(w, r)
 			return
@@ -62,41 +94,69 @@ func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) h
 
 		apikey := r.Header.Get("apikey")
 		if apikey != "" {
-			check := helper.VerifyAPIKey(apikey)
+			name, check := helper.FindAPIKey(apikey, cfg.APIKeyHashes())
 			if check {
+				// API key callers have admin access
+				ctx := helper.WithJWTInfo(r.Context(), helper.JWT_INFO{IsAdmin: true})
+				r = r.WithContext(helper.WithAPIKey(ctx, name))
 				// Middleware successful
 				next.ServeHTTP(w, r)
 				return
 			}
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Unauthorized")
 			logger.Error(fmt.Errorf("Unauthorized"), http.StatusUnauthorized)
 			return
 		}
 
 		auth := r.Header.Get("Authorization")
 		if auth == "" {
-			http.Error(w, "Unauthorized - Error no token provided", http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.Unauthorized, "No token provided.")
 			logger.Error(fmt.Errorf("Unauthorized - Error no token provided"), http.StatusUnauthorized)
 			return
 		}
 		token, err := helper.CleanJWT(r.Header.Get("Authorization"))
 		if err != nil {
-			http.Error(w, "Unauthorized - Error "+err.Error(), http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Authorization header must be a Bearer token.")
 			logger.Error(fmt.Errorf("Error while cleaning jwt "+err.Error()), http.StatusUnauthorized)
 			return
 		}
 
-		ok, jwtInfo, err := helper.VerifyJWT(token)
+		ok, jwtInfo, err := helper.VerifyJWT(token, cfg.JWTSecret)
 		if err != nil || !ok {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Unauthorized")
 			if err != nil {
 				logger.Error(fmt.Errorf("Error while verifying jwt "+err.Error()), http.StatusUnauthorized)
 			}
 			return
 		}
 
+		// A deactivated or deleted user's token stops working straight away, and admin rights
+		// granted or revoked since it was issued take effect
+		caller, err := active_caller(r.Context(), jwtInfo.Email, collection)
+		if err == mongo.ErrNoDocuments {
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Unauthorized")
+			logger.Error(fmt.Errorf("Unauthorized - token of a deactivated or unknown user "+jwtInfo.Email), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting user from DB")
+			logger.Error(fmt.Errorf("Error getting user "+jwtInfo.Email+": "+err.Error()), http.StatusInternalServerError)
+			return
+		}
+		jwtInfo.IsAdmin = caller.IsAdmin
+
+		// Let the handlers see who is calling
+		r = r.WithContext(helper.WithJWTInfo(r.Context(), jwtInfo))
+
+		// Only admins can read the audit log
+		if strings.HasPrefix(path, "/audit") && !jwtInfo.IsAdmin {
+			apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
+			logger.Error(fmt.Errorf("Forbidden - audit log requested by "+jwtInfo.Email), http.StatusForbidden)
+			return
+		}
+
 		// Role based access for courses endpoints
-		if strings.Contains(r.URL.Path, "/courses") {
+		if strings.Contains(path, "/courses") {
 
 			// Get is allowed with valid jwt
 			if r.Method == "GET" {
@@ -105,15 +165,15 @@ func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) h
 			}
 
 			// CRUD for courses is only allowed for admins
-			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 				logger.Error(fmt.Errorf("Forbidden - CRUD operation on courses  requested by "+jwtInfo.Email), http.StatusForbidden)
 				return
 			}
 		}
 
 		// Role based access for users endpoints
-		if strings.Contains(r.URL.Path, "/classrooms") {
+		if strings.Contains(path, "/classrooms") {
 
 			// get forbidden for jwt
 			if r.Method == "GET" {
@@ -122,29 +182,74 @@ func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) h
 			}
 
 			// user must be admin for CRUD operation on classrooms
-			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 				logger.Error(fmt.Errorf("Forbidden - CRUD operation requested by "+jwtInfo.Email), http.StatusForbidden)
 				return
 			}
 
 		}
 
+		// Role based access for terms endpoints
+		if strings.Contains(path, "/terms") {
+			if r.Method == "GET" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// user must be admin for CRUD operation on terms
+			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
+				logger.Error(fmt.Errorf("Forbidden - CRUD operation requested for terms by "+jwtInfo.Email), http.StatusForbidden)
+				return
+			}
+		}
+
+		// Role based access for buildings endpoints
+		if strings.Contains(path, "/buildings") {
+			if r.Method == "GET" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// user must be admin for CRUD operation on buildings
+			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
+				logger.Error(fmt.Errorf("Forbidden - CRUD operation requested for buildings by "+jwtInfo.Email), http.StatusForbidden)
+				return
+			}
+		}
+
+		// Role based access for blocks endpoints
+		if strings.Contains(path, "/blocks") {
+			if r.Method == "GET" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// user must be admin for CRUD operation on blocks
+			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
+				logger.Error(fmt.Errorf("Forbidden - CRUD operation requested for blocks by "+jwtInfo.Email), http.StatusForbidden)
+				return
+			}
+		}
+
 		// Role based access for previous schedules endpoints
-		if r.URL.Path == "/schedules/prev" {
+		if path == "/schedules/prev" {
 			if r.Method == "GET" {
 				next.ServeHTTP(w, r)
 				return
 			}
 			if r.Method == "POST" && !jwtInfo.IsAdmin {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 				logger.Error(fmt.Errorf("Forbidden - /schedules/prev CRUD operation requested by non admin - "+jwtInfo.Email), http.StatusForbidden)
 				return
 			}
 		}
 
 		// Role based access for schedules endpoints
-		if strings.Contains(r.URL.Path, "/schedules") && r.URL.Path != "/schedules/prev" {
+		if strings.Contains(path, "/schedules") && path != "/schedules/prev" {
 			// get forbidden for jwt
 			if r.Method == "GET" {
 				next.ServeHTTP(w, r)
@@ -152,18 +257,25 @@ func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) h
 			}
 
 			// user must be admin for CRUD operation on schedules
-			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+			if (r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 				logger.Error(fmt.Errorf("Forbidden - CRUD operation requested for schedules by "+jwtInfo.Email), http.StatusForbidden)
 				return
 			}
 		}
 
 		// Role Based access for users endpoint
-		if strings.Contains(r.URL.Path, "/users") {
+		if strings.Contains(path, "/users") {
+			// only admins can deactivate users or change roles, even their own
+			if (r.Method == "DELETE" || adminOnlyUserRoute(path)) && !jwtInfo.IsAdmin {
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
+				logger.Error(fmt.Errorf("Forbidden - user deactivation or role change requested by "+jwtInfo.Email), http.StatusForbidden)
+				return
+			}
+
 			// user must be admin for CRUD operation on users
 			if !valid_permissions(r, jwtInfo.IsAdmin, jwtInfo.Email, collection) {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 				logger.Error(fmt.Errorf("Forbidden - CRUD operation requested by "+jwtInfo.Email), http.StatusForbidden)
 				return
 			}
@@ -171,9 +283,7 @@ func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) h
 			// if user is not admin then they can only get their
 			if (r.Method == "GET") && !jwtInfo.IsAdmin {
 				// Extract the user username from the URL path
-				path := r.URL.Path
-				username := strings.TrimPrefix(path, "/users/")
-				username = strings.TrimSpace(username)
+				username := strings.TrimSpace(mux.Vars(r)["username"])
 
 				// NOTE: check for get all user from non admin
 
@@ -186,19 +296,19 @@ func Users_API_Access_Control(next http.Handler, collection *mongo.Collection) h
 						// If the user is not found,
 						// log the error and return a not found response
 						logger.Error(fmt.Errorf("User not found"), http.StatusNotFound)
-						http.Error(w, "Forbidden", http.StatusForbidden)
+						apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 					} else {
 						// If there is an error retrieving the user,
 						// log the error and return an internal server error response
 						logger.Error(fmt.Errorf("Error getting user"), http.StatusInternalServerError)
-						http.Error(w, "Error getting user from DB", http.StatusInternalServerError)
+						apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting user from DB")
 					}
 					return
 				}
 
 				if user.Email != jwtInfo.Email {
 					logger.Error(fmt.Errorf("Forbidden, non admin user trying to access other users info"), http.StatusForbidden)
-					http.Error(w, "Forbidden", http.StatusForbidden)
+					apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "Forbidden")
 					return
 				}
 
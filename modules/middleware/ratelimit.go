@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// bucket holds the requests a client has left under one limit
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled completely, after which it can be forgotten
+	full time.Time
+}
+
+// namedLimit is a limit and the prefix of the buckets it keeps
+type namedLimit struct {
+	name  string
+	limit config.RateLimit
+}
+
+// RateLimiter limits how often each client calls the API. The buckets are kept in memory, so each
+// instance of the server counts the requests it serves.
+type RateLimiter struct {
+	cfg    *config.Config
+	routes map[string]namedLimit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewRateLimiter returns a limiter using the rate limits of the configuration
+func NewRateLimiter(cfg *config.Config) *RateLimiter {
+	return &RateLimiter{
+		cfg: cfg,
+		// Routes with a stricter limit of their own, by method and path template. Password resets
+		// share the login bucket, they are another way to guess at an account.
+		routes: map[string]namedLimit{
+			"POST /login":                            {name: "login", limit: cfg.RateLimit.Login},
+			"POST /password/forgot":                  {name: "login", limit: cfg.RateLimit.Login},
+			"POST /password/reset":                   {name: "login", limit: cfg.RateLimit.Login},
+			"POST /schedules/{year}/{term}/generate": {name: "generate", limit: cfg.RateLimit.Generate},
+		},
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Limit is middleware rejecting requests from clients that have used up their requests with 429.
+// Every response carries the RateLimit headers of the limit closest to running out.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	if !l.cfg.RateLimit.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits := []namedLimit{{name: "general", limit: l.cfg.RateLimit.General}}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				if limit, ok := l.routes[r.Method+" "+versionPrefix.ReplaceAllString(template, "")]; ok {
+					limits = append(limits, limit)
+				}
+			}
+		}
+
+		client := l.client(r)
+		allowed, limit, remaining, wait := l.take(client, limits, time.Now())
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		header.Set("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(refillTime(limit, remaining))))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, seconds(limit.Period.Duration), limit.Burst))
+		if !allowed {
+			header.Set("Retry-After", strconv.Itoa(seconds(wait)))
+			logger.Error(fmt.Errorf("rate limit reached by "+client+" on "+r.Method+" "+r.URL.Path), http.StatusTooManyRequests)
+			apierror.Write(w, r, http.StatusTooManyRequests, apierror.RateLimited, "Too many requests, try again in "+strconv.Itoa(seconds(wait))+" seconds.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// client names who is calling: the API key, the user in the token, or else the address. Invalid
+// credentials count against the address, as access control will reject them anyway.
+func (l *RateLimiter) client(r *http.Request) string {
+	if apikey := r.Header.Get("apikey"); apikey != "" {
+		if name, ok := helper.FindAPIKey(apikey, l.cfg.APIKeyHashes()); ok {
+			return "apikey:" + name
+		}
+	} else if token, err := helper.CleanJWT(r.Header.Get("Authorization")); err == nil {
+		if ok, info, err := helper.VerifyJWT(token, l.cfg.JWTSecret); err == nil && ok && info.Email != "" {
+			return "user:" + info.Email
+		}
+	}
+	return "ip:" + helper.ClientIP(r, l.cfg.Server.TrustProxyHeaders)
+}
+
+// take removes a request from each of the client's buckets if every one of them has one left. It
+// returns the limit closest to running out, what is left of it, and how long until the request
+// would be allowed if it isn't.
+func (l *RateLimiter) take(client string, limits []namedLimit, now time.Time) (bool, config.RateLimit, float64, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	buckets := make([]*bucket, len(limits))
+	allowed := true
+	var wait time.Duration
+	for i, named := range limits {
+		buckets[i] = l.refill(named.name+"|"+client, named.limit, now)
+		if buckets[i].tokens < 1 {
+			allowed = false
+			if delay := timeToEarn(named.limit, 1-buckets[i].tokens); delay > wait {
+				wait = delay
+			}
+		}
+	}
+
+	closest := 0
+	for i, b := range buckets {
+		if allowed {
+			b.tokens--
+			b.full = now.Add(refillTime(limits[i].limit, b.tokens))
+		}
+		if b.tokens/float64(limits[i].limit.Burst) < buckets[closest].tokens/float64(limits[closest].limit.Burst) {
+			closest = i
+		}
+	}
+	return allowed, limits[closest].limit, math.Floor(buckets[closest].tokens), wait
+}
+
+// refill returns the bucket with the requests earned since it was last used added back
+func (l *RateLimiter) refill(key string, limit config.RateLimit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now, full: now}
+		l.buckets[key] = b
+		return b
+	}
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	return b
+}
+
+// sweep forgets the buckets that have refilled, once a minute, so idle clients don't use memory.
+// A forgotten bucket starts full again, which is what it would have been.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// refillTime is how long a bucket of the limit holding tokens takes to fill up
+func refillTime(limit config.RateLimit, tokens float64) time.Duration {
+	return timeToEarn(limit, float64(limit.Burst)-tokens)
+}
+
+// timeToEarn is how long the limit takes to give back the number of requests
+func timeToEarn(limit config.RateLimit, requests float64) time.Duration {
+	if requests <= 0 {
+		return 0
+	}
+	return time.Duration(requests / float64(limit.Requests) * float64(limit.Period.Duration))
+}
+
+// seconds rounds the duration up to whole seconds for the headers
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
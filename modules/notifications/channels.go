@@ -0,0 +1,148 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+	"github.com/SENG-499-Company2-B01/Backend/modules/mail"
+)
+
+// Names of the channels notifications are delivered through
+const (
+	Email   = "email"
+	Inbox   = "inbox"
+	Webhook = "webhook"
+)
+
+// ChannelNames lists every channel, in the order they are documented
+var ChannelNames = []string{Email, Inbox, Webhook}
+
+// Channel delivers a notification to its recipient. An error means the delivery is tried again
+// later, so delivering the same notification twice must be harmless where it can be.
+type Channel interface {
+	Deliver(ctx context.Context, delivery Delivery) error
+}
+
+// EmailChannel sends notifications as email
+type EmailChannel struct {
+	Mailer mail.Mailer
+}
+
+// Deliver - emails the notification to the recipient
+func (channel EmailChannel) Deliver(ctx context.Context, delivery Delivery) error {
+	if delivery.Email == "" {
+		return errors.New("recipient has no email address")
+	}
+	return channel.Mailer.Send(mail.Message{To: delivery.Email, Subject: delivery.Subject, Body: delivery.Body})
+}
+
+// InboxChannel stores notifications for GET /users/{username}/notifications
+type InboxChannel struct {
+	Inbox *mongo.Collection
+}
+
+// Deliver - adds the notification to the recipient's inbox. The notification has the ID of the
+// delivery, so a retried delivery doesn't add it twice.
+func (channel InboxChannel) Deliver(ctx context.Context, delivery Delivery) error {
+	_, err := channel.Inbox.InsertOne(ctx, Notification{
+		ID:        delivery.ID,
+		Username:  delivery.Username,
+		Type:      delivery.Event.Type,
+		Subject:   delivery.Subject,
+		Body:      delivery.Body,
+		Event:     delivery.Event,
+		CreatedAt: delivery.CreatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook request body, keyed with the webhook secret
+const SignatureHeader = "X-Signature-256"
+
+// webhookTimeout is how long a webhook has to answer before the delivery is tried again
+const webhookTimeout = 10 * time.Second
+
+// WebhookChannel posts notifications as JSON to a URL. Each request is signed so the receiver can
+// check it came from the backend.
+type WebhookChannel struct {
+	URL    string
+	Secret string
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// webhookPayload is the body of a webhook request
+type webhookPayload struct {
+	ID        string       `json:"id"`
+	Username  string       `json:"username"`
+	Type      string       `json:"type"`
+	Subject   string       `json:"subject"`
+	Body      string       `json:"body"`
+	Event     events.Event `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Sign returns the value of SignatureHeader for the body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver - posts the notification to the webhook, any response but a 2xx is a failure
+func (channel WebhookChannel) Deliver(ctx context.Context, delivery Delivery) error {
+	body, err := json.Marshal(webhookPayload{
+		ID:        delivery.ID,
+		Username:  delivery.Username,
+		Type:      delivery.Event.Type,
+		Subject:   delivery.Subject,
+		Body:      delivery.Body,
+		Event:     delivery.Event,
+		CreatedAt: delivery.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(channel.Secret, body))
+	// Lets the receiver ignore a delivery it has already handled
+	req.Header.Set("X-Notification-ID", delivery.ID)
+
+	client := channel.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// Names of the collections the notifications are kept in
+const (
+	OutboxCollection      = "notification_outbox"
+	InboxCollection       = "notifications"
+	PreferencesCollection = "notification_preferences"
+)
+
+// Notification is a notification in a user's inbox
+type Notification struct {
+	ID        string       `json:"id" bson:"_id"`
+	Username  string       `json:"username" bson:"username"`
+	Type      string       `json:"type" bson:"type"`
+	Subject   string       `json:"subject" bson:"subject"`
+	Body      string       `json:"body" bson:"body"`
+	Event     events.Event `json:"event" bson:"event"`
+	CreatedAt time.Time    `json:"created_at" bson:"created_at"`
+	Read      bool         `json:"read" bson:"read"`
+	ReadAt    *time.Time   `json:"read_at,omitempty" bson:"read_at,omitempty"`
+}
+
+// Preferences are the channels a user wants each type of notification through. A type left out
+// is sent through every channel, an empty list turns it off.
+type Preferences struct {
+	Username string              `json:"username" bson:"username"`
+	Channels map[string][]string `json:"channels" bson:"channels"`
+}
+
+// find returns the user's preferences with every type filled in
+func find(ctx context.Context, collection *mongo.Collection, username string) (Preferences, error) {
+	var stored Preferences
+	err := collection.FindOne(ctx, bson.M{"username": username}).Decode(&stored)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return Preferences{}, err
+	}
+
+	preferences := Preferences{Username: username, Channels: map[string][]string{}}
+	for _, eventType := range events.Types {
+		channels, ok := stored.Channels[eventType]
+		if !ok {
+			channels = ChannelNames
+		}
+		preferences.Channels[eventType] = append([]string{}, channels...)
+	}
+	return preferences, nil
+}
+
+// validate returns the problems with the channels of a preferences update
+func (preferences Preferences) validate() []string {
+	types := map[string]bool{}
+	for _, eventType := range events.Types {
+		types[eventType] = true
+	}
+	channels := map[string]bool{}
+	for _, channel := range ChannelNames {
+		channels[channel] = true
+	}
+
+	var problems []string
+	for eventType, chosen := range preferences.Channels {
+		if !types[eventType] {
+			problems = append(problems, fmt.Sprintf("channels has unknown notification type %q, must be one of %s", eventType, strings.Join(events.Types, ", ")))
+			continue
+		}
+		for _, channel := range chosen {
+			if !channels[channel] {
+				problems = append(problems, fmt.Sprintf("channels.%s has unknown channel %q, must be one of %s", eventType, channel, strings.Join(ChannelNames, ", ")))
+			}
+		}
+	}
+	// Maps are iterated in random order
+	sort.Strings(problems)
+	return problems
+}
+
+// notificationList - the filters and sort keys of GET /users/{username}/notifications
+var notificationList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "read", Field: "read", Kind: helper.Bool},
+		{Param: "type", Field: "type", Kind: helper.Exact},
+		{Param: "since", Field: "created_at", Kind: helper.Since},
+	},
+	Sorts:       map[string]string{"created_at": "created_at"},
+	DefaultSort: "-created_at",
+}
+
+// GetNotifications - lists the notifications in the user's inbox, newest first
+func GetNotifications(w http.ResponseWriter, r *http.Request, inbox *mongo.Collection) {
+	logger.Info("GetNotifications function called.")
+
+	query, err := helper.ParseListQuery(r, notificationList)
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
+		return
+	}
+	query.Filter["username"] = strings.TrimSpace(mux.Vars(r)["username"])
+
+	page, err := helper.FindPage[Notification](context.TODO(), inbox, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving notifications: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving notifications.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// MarkNotificationRead - marks a notification in the user's inbox as read
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request, inbox *mongo.Collection) {
+	logger.Info("MarkNotificationRead function called.")
+
+	vars := mux.Vars(r)
+	filter := bson.M{"_id": vars["id"], "username": strings.TrimSpace(vars["username"])}
+
+	// Reading it again keeps the time it was first read
+	var notification Notification
+	err := inbox.FindOneAndUpdate(context.TODO(), filter,
+		bson.A{bson.M{"$set": bson.M{"read": true, "read_at": bson.M{"$ifNull": bson.A{"$read_at", time.Now().UTC()}}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		logger.Error(fmt.Errorf("notification not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Notification not found.")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating the notification: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating the notification.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notification)
+}
+
+// GetPreferences - returns the channels the user receives each type of notification through
+func GetPreferences(w http.ResponseWriter, r *http.Request, users_coll *mongo.Collection, collection *mongo.Collection) {
+	logger.Info("GetPreferences function called.")
+
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+	if !userFound(w, r, users_coll, username) {
+		return
+	}
+
+	preferences, err := find(context.TODO(), collection, username)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving notification preferences: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving notification preferences.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// UpdatePreferences - replaces the channels the user receives each type of notification through
+func UpdatePreferences(w http.ResponseWriter, r *http.Request, users_coll *mongo.Collection, collection *mongo.Collection) {
+	logger.Info("UpdatePreferences function called.")
+
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+
+	var request Preferences
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.", helper.DecodeProblem(err))
+		return
+	}
+	problems := request.validate()
+	if request.Username != "" && request.Username != username {
+		problems = append(problems, "username can't be changed")
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid notification preferences: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid notification preferences.", apierror.Problems(problems)...)
+		return
+	}
+
+	if !userFound(w, r, users_coll, username) {
+		return
+	}
+
+	stored := Preferences{Username: username, Channels: map[string][]string{}}
+	for eventType, channels := range request.Channels {
+		// null turns a type off like an empty list
+		stored.Channels[eventType] = append([]string{}, channels...)
+	}
+	_, err = collection.ReplaceOne(context.TODO(), bson.M{"username": username}, stored, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating notification preferences: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating notification preferences.")
+		return
+	}
+
+	preferences, err := find(context.TODO(), collection, username)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving notification preferences: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving notification preferences.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// userFound writes a 404 and returns false unless the username belongs to an active user
+func userFound(w http.ResponseWriter, r *http.Request, users_coll *mongo.Collection, username string) bool {
+	count, err := users_coll.CountDocuments(context.TODO(), bson.M{"username": username, "deactivated_at": nil})
+	if err != nil {
+		logger.Error(fmt.Errorf("Error querying collection: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error querying collection.")
+		return false
+	}
+	if count == 0 {
+		logger.Error(fmt.Errorf("user not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "User not found.")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,258 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+	"github.com/SENG-499-Company2-B01/Backend/modules/mail"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// Statuses of a delivery in the outbox
+const (
+	Pending = "pending"
+	Sent    = "sent"
+	Failed  = "failed"
+)
+
+// deliveryLease is how long a claimed delivery is left alone before another worker may try it,
+// in case the one that claimed it stopped before recording the result
+const deliveryLease = time.Minute
+
+// Delivery is a notification waiting in the outbox to be sent through one channel to one user
+type Delivery struct {
+	ID          string       `bson:"_id"`
+	Channel     string       `bson:"channel"`
+	Username    string       `bson:"username"`
+	Email       string       `bson:"email"`
+	Event       events.Event `bson:"event"`
+	Subject     string       `bson:"subject"`
+	Body        string       `bson:"body"`
+	Status      string       `bson:"status"`
+	Attempts    int          `bson:"attempts"`
+	NextAttempt time.Time    `bson:"next_attempt_at"`
+	LastError   string       `bson:"last_error,omitempty"`
+	CreatedAt   time.Time    `bson:"created_at"`
+	SentAt      *time.Time   `bson:"sent_at,omitempty"`
+}
+
+// Service turns events into deliveries in the outbox and delivers them through the channels,
+// trying failed deliveries again with a growing backoff
+type Service struct {
+	Users       *mongo.Collection
+	Outbox      *mongo.Collection
+	Preferences *mongo.Collection
+	// Channels are the configured channels by name, a channel left out is never used
+	Channels     map[string]Channel
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	PollInterval time.Duration
+}
+
+// message is what the mail templates of the events are rendered with
+type message struct {
+	events.Event
+	// Name is the recipient's name, or their username if it isn't known
+	Name string
+	// Assigned is set when the recipient was given the reassigned section rather than losing it
+	Assigned bool
+}
+
+// Handle - queues a delivery of the event for every recipient and every channel they chose. It
+// subscribes to the events package, so it only writes to the outbox and leaves sending to Run.
+func (service *Service) Handle(_ context.Context, event events.Event) {
+	// The outbox is written even if the request that caused the event is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := service.enqueue(ctx, event)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error queueing "+event.Type+" notifications: "+err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// enqueue inserts the deliveries of the event into the outbox
+func (service *Service) enqueue(ctx context.Context, event events.Event) error {
+	recipients, err := service.recipients(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []interface{}
+	for _, user := range recipients {
+		channels, err := service.channelsFor(ctx, user.Username, event.Type)
+		if err != nil {
+			return err
+		}
+		if len(channels) == 0 {
+			continue
+		}
+
+		data := message{Event: event, Name: user.Name, Assigned: event.To != "" && (event.To == user.Username || event.To == user.Name)}
+		if data.Name == "" {
+			data.Name = user.Username
+		}
+		rendered, err := mail.Compose(user.Email, event.Type, data)
+		if err != nil {
+			return err
+		}
+
+		for _, channel := range channels {
+			deliveries = append(deliveries, Delivery{
+				ID:          primitive.NewObjectID().Hex(),
+				Channel:     channel,
+				Username:    user.Username,
+				Email:       user.Email,
+				Event:       event,
+				Subject:     rendered.Subject,
+				Body:        rendered.Body,
+				Status:      Pending,
+				NextAttempt: now,
+				CreatedAt:   now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	_, err = service.Outbox.InsertMany(ctx, deliveries)
+	return err
+}
+
+// recipients returns the active users the event concerns. Schedules name professors by name, or
+// by username if the name wasn't known, so both are matched.
+func (service *Service) recipients(ctx context.Context, event events.Event) ([]users.User, error) {
+	var filter bson.M
+	switch {
+	case event.Username != "":
+		filter = bson.M{"username": event.Username}
+	case len(event.Professors) > 0:
+		filter = bson.M{"$or": bson.A{
+			bson.M{"username": bson.M{"$in": event.Professors}},
+			bson.M{"name": bson.M{"$in": event.Professors}},
+		}}
+	default:
+		return nil, nil
+	}
+	filter["deactivated_at"] = nil
+
+	var recipients []users.User
+	cursor, err := service.Users.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &recipients)
+	return recipients, err
+}
+
+// channelsFor returns the configured channels the user wants notifications of the type through
+func (service *Service) channelsFor(ctx context.Context, username string, eventType string) ([]string, error) {
+	preferences, err := find(ctx, service.Preferences, username)
+	if err != nil {
+		return nil, err
+	}
+	var channels []string
+	for _, channel := range preferences.Channels[eventType] {
+		if service.Channels[channel] != nil {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+// DeliverDue - sends every delivery that is due, returning how many were sent. Deliveries are
+// claimed one at a time, so several backends can share the outbox.
+func (service *Service) DeliverDue(ctx context.Context) (int, error) {
+	sent := 0
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		var delivery Delivery
+		err := service.Outbox.FindOneAndUpdate(ctx,
+			bson.M{"status": Pending, "next_attempt_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"next_attempt_at": now.Add(deliveryLease)}, "$inc": bson.M{"attempts": 1}},
+			options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).SetReturnDocument(options.After),
+		).Decode(&delivery)
+		if err == mongo.ErrNoDocuments {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		err = service.deliver(ctx, delivery)
+		if err != nil {
+			return sent, err
+		}
+		if ctx.Err() == nil {
+			sent++
+		}
+	}
+	return sent, ctx.Err()
+}
+
+// deliver sends the claimed delivery and records the result. The returned error is about
+// recording it, a failed send is only logged.
+func (service *Service) deliver(ctx context.Context, delivery Delivery) error {
+	now := time.Now().UTC()
+	update := bson.M{"$set": bson.M{"status": Sent, "sent_at": now}, "$unset": bson.M{"last_error": ""}}
+
+	var err error
+	channel := service.Channels[delivery.Channel]
+	if channel == nil {
+		err = fmt.Errorf("channel %q is not configured", delivery.Channel)
+	} else {
+		err = channel.Deliver(ctx, delivery)
+	}
+	if err != nil {
+		logger.Warning(fmt.Sprintf("Delivering %s notification %s to %s failed (attempt %d): %s", delivery.Channel, delivery.ID, delivery.Username, delivery.Attempts, err.Error()))
+		set := bson.M{"last_error": err.Error()}
+		if delivery.Attempts >= service.MaxAttempts {
+			set["status"] = Failed
+		} else {
+			set["next_attempt_at"] = now.Add(service.backoff(delivery.Attempts))
+		}
+		update = bson.M{"$set": set}
+	}
+
+	// Recorded even if ctx was cancelled by a shutdown during the send
+	recordCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = service.Outbox.UpdateOne(recordCtx, bson.M{"_id": delivery.ID}, update)
+	return err
+}
+
+// backoff returns how long to wait after the given number of failed attempts, doubling each time
+func (service *Service) backoff(attempts int) time.Duration {
+	wait := service.RetryBackoff
+	for i := 1; i < attempts && wait < 24*time.Hour; i++ {
+		wait *= 2
+	}
+	return wait
+}
+
+// Run - delivers due notifications every PollInterval until the context is cancelled
+func (service *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(service.PollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := service.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error(fmt.Errorf("Error delivering notifications: "+err.Error()), http.StatusInternalServerError)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
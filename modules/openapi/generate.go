@@ -0,0 +1,228 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
+)
+
+// Version is the version of the API the document describes
+const Version = "1.0.0"
+
+// Types whose JSON form isn't their struct fields
+var (
+	dateType     = reflect.TypeOf(terms.Date{})
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+var pathParameter = regexp.MustCompile(`\{([^}:]+)`)
+
+// generator collects the component schemas while the operations are described
+type generator struct {
+	schemas map[string]interface{}
+}
+
+// Generate - builds the OpenAPI document from the operations and the Go types they use
+func Generate() ([]byte, error) {
+	g := generator{schemas: map[string]interface{}{}}
+
+	paths := map[string]map[string]interface{}{}
+	for _, op := range Operations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]interface{}{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = g.operation(op)
+		if op.Unversioned {
+			paths[op.Path]["servers"] = []interface{}{map[string]interface{}{"url": "/"}}
+		}
+	}
+	for _, value := range Schemas {
+		g.schema(reflect.TypeOf(value))
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Scheduling Application Backend",
+			"version":     Version,
+			"description": "Generated from the routes and Go types of the backend by go generate ./modules/openapi.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": BasePath}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "Error, see the README for the codes",
+					"content":     jsonContent(g.schema(reflect.TypeOf(apierror.Response{}))),
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]interface{}{"type": "apiKey", "in": "header", "name": "apikey"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+		},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// operation describes one route
+func (g *generator) operation(op Operation) map[string]interface{} {
+	parameters := []interface{}{}
+	for _, match := range pathParameter.FindAllStringSubmatch(op.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if op.IfMatch {
+		parameters = append(parameters, map[string]interface{}{
+			"name": "If-Match", "in": "header", "required": true, "schema": map[string]interface{}{"type": "string"},
+			"description": "ETag of the version being changed",
+		})
+	}
+	for _, name := range op.Query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+
+	ok := map[string]interface{}{"description": "OK"}
+	if op.Response != nil {
+		schema := g.schema(reflect.TypeOf(op.Response))
+		if op.List {
+			schema = map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"items":       map[string]interface{}{"type": "array", "items": schema},
+					"next_cursor": map[string]interface{}{"type": "string"},
+					"total":       map[string]interface{}{"type": "integer"},
+				},
+			}
+		}
+		ok["content"] = jsonContent(schema)
+	}
+
+	operation := map[string]interface{}{
+		"tags":       []string{op.Tag},
+		"summary":    op.Summary,
+		"parameters": parameters,
+		"responses": map[string]interface{}{
+			"200":     ok,
+			"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+		},
+	}
+	if op.Request != nil {
+		content := jsonContent(g.schema(reflect.TypeOf(op.Request)))
+		// PATCH bodies are JSON merge patches of the resource
+		if op.Method == "PATCH" {
+			content = map[string]interface{}{"application/merge-patch+json": content["application/json"]}
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  content,
+		}
+	}
+	if op.Public {
+		operation["security"] = []interface{}{}
+	}
+	return operation
+}
+
+// schema returns the JSON schema of a Go type, structs are added to the components and referenced
+func (g *generator) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case dateType:
+		return map[string]interface{}{"type": "string", "format": "date"}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case objectIDType:
+		return map[string]interface{}{"type": "string", "pattern": "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name first so recursive types refer to themselves
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	// Interfaces can hold anything
+	return map[string]interface{}{}
+}
+
+// object describes the JSON fields of a struct
+func (g *generator) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	g.fields(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+}
+
+func (g *generator) fields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		// Embedded structs without a name of their own are flattened, like encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.fields(field.Type, properties)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+	}
+}
+
+// schemaName names a struct after its package, e.g. "courses.Course"
+func schemaName(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
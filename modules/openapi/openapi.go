@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:generate go run ../../cmd/openapi -o openapi.json
+
+// spec is the generated document, a test fails if it is out of date
+//
+//go:embed openapi.json
+var spec []byte
+
+// Document - returns the OpenAPI document served at /openapi.json
+func Document() []byte {
+	return spec
+}
+
+// Spec - serves the OpenAPI document
+func Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(spec)
+}
+
+// docsPage loads Swagger UI from a CDN and points it at the document next to it
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Scheduling Application Backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// Docs - serves a Swagger UI page for the OpenAPI document
+func Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(docsPage))
+}
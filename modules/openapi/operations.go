@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/blocks"
+	"github.com/SENG-499-Company2-B01/Backend/modules/buildings"
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/notifications"
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+	"github.com/SENG-499-Company2-B01/Backend/modules/search"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// Operation describes one route of the API. Request and Response are values of the Go types
+// of the bodies, their schemas are generated from the structs.
+type Operation struct {
+	Method   string
+	Path     string
+	Tag      string
+	Summary  string
+	Query    []string
+	Request  interface{}
+	Response interface{}
+	// List responses are a page of Response items
+	List bool
+	// Public operations need no token or API key
+	Public bool
+	// Unversioned operations are served at the root instead of under BasePath
+	Unversioned bool
+	// IfMatch operations require the ETag of the version being changed
+	IfMatch bool
+}
+
+// BasePath is where the version of the API the document describes is mounted. Its routes are
+// also served without the prefix, as deprecated aliases.
+const BasePath = "/api/v1"
+
+// list adds the pagination parameters every list endpoint takes to its filters
+func list(filters ...string) []string {
+	return append([]string{"limit", "cursor", "sort", "fields"}, filters...)
+}
+
+// Operations is every route registered by main.go, relative to BasePath. The route test fails if
+// they differ.
+var Operations = []Operation{
+	{Method: "POST", Path: "/login", Tag: "auth", Summary: "Log in and get a JWT", Request: users.User{}, Response: map[string]string{}, Public: true},
+	{Method: "POST", Path: "/logout", Tag: "auth", Summary: "Log out", Public: true},
+	{Method: "POST", Path: "/password/forgot", Tag: "auth", Summary: "Email a link to reset a forgotten password", Request: users.ForgotPasswordRequest{}, Public: true},
+	{Method: "POST", Path: "/password/reset", Tag: "auth", Summary: "Set a new password with the token from a reset link", Request: users.ResetPasswordRequest{}, Public: true},
+
+	{Method: "POST", Path: "/users", Tag: "users", Summary: "Create a user", Request: users.User{}},
+	{Method: "GET", Path: "/users", Tag: "users", Summary: "List users", Query: list("username", "peng", "pref_approved"), Response: users.User{}, List: true},
+	{Method: "GET", Path: "/users/{username}", Tag: "users", Summary: "Get a user", Response: users.User{}},
+	{Method: "PUT", Path: "/users/{username}", Tag: "users", Summary: "Replace a user", Request: users.User{}, IfMatch: true},
+	{Method: "PATCH", Path: "/users/{username}", Tag: "users", Summary: "Change some fields of a user with a JSON merge patch", Request: users.User{}, IfMatch: true},
+	{Method: "DELETE", Path: "/users/{username}", Tag: "users", Summary: "Deactivate a user"},
+	{Method: "GET", Path: "/users/{username}/admin", Tag: "users", Summary: "Tell whether a user is an admin, with the history of their admin rights", Response: users.AdminRole{}},
+	{Method: "PUT", Path: "/users/{username}/admin", Tag: "users", Summary: "Make a user an admin"},
+	{Method: "DELETE", Path: "/users/{username}/admin", Tag: "users", Summary: "Take admin rights away from a user"},
+	{Method: "POST", Path: "/users/{username}/unlock", Tag: "users", Summary: "Clear the failed logins of a user, lifting a lockout"},
+	{Method: "POST", Path: "/users/{username}/reactivate", Tag: "users", Summary: "Reactivate a deactivated user"},
+	{Method: "GET", Path: "/users/{username}/notifications", Tag: "notifications", Summary: "List the notifications in a user's inbox, newest first", Query: list("read", "type", "since"), Response: notifications.Notification{}, List: true},
+	{Method: "POST", Path: "/users/{username}/notifications/{id}/read", Tag: "notifications", Summary: "Mark a notification as read", Response: notifications.Notification{}},
+	{Method: "GET", Path: "/users/{username}/notification-preferences", Tag: "notifications", Summary: "Get the channels a user receives each type of notification through", Response: notifications.Preferences{}},
+	{Method: "PUT", Path: "/users/{username}/notification-preferences", Tag: "notifications", Summary: "Choose the channels a user receives each type of notification through", Request: notifications.Preferences{}, Response: notifications.Preferences{}},
+	{Method: "GET", Path: "/users/deactivated/sections", Tag: "users", Summary: "List the draft schedule sections still assigned to deactivated users", Response: schedules.OrphanedSections{}},
+
+	{Method: "POST", Path: "/classrooms", Tag: "classrooms", Summary: "Create a classroom", Request: classrooms.Classroom{}},
+	{Method: "GET", Path: "/classrooms", Tag: "classrooms", Summary: "List classrooms", Query: list("building", "room_type", "features", "min_capacity", "max_capacity"), Response: classrooms.Classroom{}, List: true},
+	{Method: "GET", Path: "/classrooms/{building}/{room}", Tag: "classrooms", Summary: "Get a classroom", Response: classrooms.Classroom{}},
+	{Method: "PUT", Path: "/classrooms/{building}/{room}", Tag: "classrooms", Summary: "Replace a classroom", Request: classrooms.Classroom{}, IfMatch: true},
+	{Method: "PATCH", Path: "/classrooms/{building}/{room}", Tag: "classrooms", Summary: "Change some fields of a classroom with a JSON merge patch", Request: classrooms.Classroom{}, IfMatch: true},
+	{Method: "DELETE", Path: "/classrooms/{building}/{room}", Tag: "classrooms", Summary: "Delete a classroom"},
+	{Method: "POST", Path: "/classrooms/{building}/{room}/rename", Tag: "classrooms", Summary: "Move a classroom to a new building and room", Request: classrooms.RenameRequest{}, Response: classrooms.RenameResult{}},
+
+	{Method: "POST", Path: "/buildings", Tag: "buildings", Summary: "Create a building", Request: buildings.Building{}, Response: buildings.Building{}},
+	{Method: "GET", Path: "/buildings", Tag: "buildings", Summary: "List buildings", Query: list("campus"), Response: buildings.Building{}, List: true},
+	{Method: "GET", Path: "/buildings/{code}", Tag: "buildings", Summary: "Get a building", Response: buildings.Building{}},
+	{Method: "PUT", Path: "/buildings/{code}", Tag: "buildings", Summary: "Replace a building", Request: buildings.Building{}, Response: buildings.Building{}},
+	{Method: "DELETE", Path: "/buildings/{code}", Tag: "buildings", Summary: "Delete a building without classrooms"},
+
+	{Method: "POST", Path: "/courses", Tag: "courses", Summary: "Create a course", Request: courses.Course{}, Response: courses.Course{}},
+	{Method: "GET", Path: "/courses", Tag: "courses", Summary: "List courses", Query: list("shorthand", "terms_offered", "peng", "room_type", "sections", "required_features"), Response: courses.Course{}, List: true},
+	{Method: "GET", Path: "/courses/{courseShortHand}", Tag: "courses", Summary: "Get a course", Response: courses.Course{}},
+	{Method: "PUT", Path: "/courses/{courseShortHand}", Tag: "courses", Summary: "Replace or rename a course", Request: courses.Course{}, Response: "", IfMatch: true},
+	{Method: "PATCH", Path: "/courses/{courseShortHand}", Tag: "courses", Summary: "Change some fields of a course with a JSON merge patch", Request: courses.Course{}, Response: "", IfMatch: true},
+	{Method: "DELETE", Path: "/courses/{courseShortHand}", Tag: "courses", Summary: "Delete a course", Query: []string{"force"}, Response: ""},
+	{Method: "GET", Path: "/courses/{courseShortHand}/prerequisites", Tag: "courses", Summary: "Get the prerequisite tree of a course", Response: courses.PrerequisiteNode{}},
+	{Method: "GET", Path: "/courses/{courseShortHand}/dependents", Tag: "courses", Summary: "List the courses that require a course", Response: courses.Dependents{}},
+
+	{Method: "POST", Path: "/terms", Tag: "terms", Summary: "Create an academic term", Request: terms.Term{}, Response: terms.Term{}},
+	{Method: "GET", Path: "/terms", Tag: "terms", Summary: "List academic terms", Query: list("year", "term"), Response: terms.Term{}, List: true},
+	{Method: "GET", Path: "/terms/{year}/{term}", Tag: "terms", Summary: "Get an academic term", Response: terms.Term{}},
+	{Method: "PUT", Path: "/terms/{year}/{term}", Tag: "terms", Summary: "Replace an academic term", Request: terms.Term{}, Response: terms.Term{}},
+	{Method: "DELETE", Path: "/terms/{year}/{term}", Tag: "terms", Summary: "Delete an academic term"},
+
+	{Method: "POST", Path: "/blocks", Tag: "blocks", Summary: "Create a curriculum block", Request: blocks.Block{}, Response: blocks.Block{}},
+	{Method: "GET", Path: "/blocks", Tag: "blocks", Summary: "List curriculum blocks", Query: list("term", "constraint", "course"), Response: blocks.Block{}, List: true},
+	{Method: "GET", Path: "/blocks/{code}", Tag: "blocks", Summary: "Get a curriculum block", Response: blocks.Block{}},
+	{Method: "PUT", Path: "/blocks/{code}", Tag: "blocks", Summary: "Replace a curriculum block", Request: blocks.Block{}, Response: blocks.Block{}},
+	{Method: "DELETE", Path: "/blocks/{code}", Tag: "blocks", Summary: "Delete a curriculum block"},
+
+	{Method: "POST", Path: "/schedules/{year}/{term}/generate", Tag: "schedules", Summary: "Generate a draft schedule", Response: schedules.Schedule{}},
+	{Method: "GET", Path: "/schedules", Tag: "schedules", Summary: "List draft schedules", Query: list("year", "term"), Response: schedules.Schedule{}, List: true},
+	{Method: "GET", Path: "/schedules/{year}/{term}", Tag: "schedules", Summary: "Get a draft schedule", Response: schedules.Schedule{}},
+	{Method: "PUT", Path: "/schedules/{year}/{term}", Tag: "schedules", Summary: "Replace a draft schedule", Request: schedules.Schedule{}, IfMatch: true},
+	{Method: "PATCH", Path: "/schedules/{year}/{term}", Tag: "schedules", Summary: "Change some fields of a draft schedule with a JSON merge patch", Request: schedules.Schedule{}, IfMatch: true},
+	{Method: "GET", Path: "/schedules/{year}/{term}/clashes", Tag: "schedules", Summary: "List the block clashes of a draft schedule", Response: schedules.Report{}},
+	{Method: "GET", Path: "/schedules/{year}/{term}/validation", Tag: "schedules", Summary: "Validate a draft schedule", Response: schedules.Report{}},
+	{Method: "GET", Path: "/schedules/prev", Tag: "schedules", Summary: "List approved schedules", Query: list("year", "term"), Response: schedules.Schedule{}, List: true},
+	{Method: "POST", Path: "/schedules/prev", Tag: "schedules", Summary: "Approve a draft schedule", Request: schedules.Frontend_Request{}},
+
+	{Method: "GET", Path: "/audit", Tag: "audit", Summary: "List the changes made through the API", Query: list("resource_type", "resource_id", "actor", "action", "request_id", "since", "until"), Response: audit.Event{}, List: true},
+
+	{Method: "GET", Path: "/search", Tag: "search", Summary: "Search courses, classrooms and users", Query: []string{"q", "limit", "types"}, Response: search.Response{}},
+
+	{Method: "GET", Path: "/health", Tag: "meta", Summary: "Health check", Public: true, Unversioned: true},
+	{Method: "GET", Path: "/openapi.json", Tag: "meta", Summary: "This document", Public: true},
+	{Method: "GET", Path: "/docs", Tag: "meta", Summary: "Swagger UI for this document", Public: true},
+}
+
+// Schemas sent to or received from the algorithm services rather than our clients. They
+// aren't used by a route but are published so the algorithm teams can rely on them.
+var Schemas = []interface{}{
+	schedules.Algs1_Request{},
+	schedules.Algs1_Schedule{},
+	schedules.Algs2_Request{},
+	schedules.Capacity{},
+}
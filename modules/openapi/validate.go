@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+)
+
+// schema is a JSON schema node of the parsed document
+type schema = map[string]interface{}
+
+// operationBody is the part of an operation the validator reads
+type operationBody struct {
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// parsed is the part of the document the validator reads. Path items also hold fields other
+// than operations, such as servers, so the operations are decoded when a route is looked up.
+type parsed struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+var (
+	parseOnce sync.Once
+	document  parsed
+	parseErr  error
+)
+
+// ValidateRequests checks JSON request bodies against the schemas of the OpenAPI document and
+// rejects those with fields of the wrong type or fields the API doesn't know with a 400
+func ValidateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parseOnce.Do(func() {
+			parseErr = json.Unmarshal(spec, &document)
+			if parseErr != nil {
+				logger.Error(fmt.Errorf("Error parsing the OpenAPI document, requests won't be validated: "+parseErr.Error()), http.StatusInternalServerError)
+			}
+		})
+
+		body := requestSchema(r)
+		if parseErr != nil || body == nil || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error reading the request body: "+err.Error()), http.StatusBadRequest)
+			apierror.WriteInvalidBody(w, r, err, "Error reading the request body.")
+			return
+		}
+		// Let the handler read the body again
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		// Handlers report a missing body themselves
+		if len(bytes.TrimSpace(data)) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var value interface{}
+		err = json.Unmarshal(data, &value)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+			apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+			return
+		}
+
+		problems := validate(body, value, "body")
+		if len(problems) > 0 {
+			logger.Error(fmt.Errorf("request doesn't match the API: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Request doesn't match the API.", apierror.Problems(problems)...)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestSchema finds the body schema of the route the request matched, nil if it has none
+func requestSchema(r *http.Request) schema {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	// The legacy aliases are registered without the prefix
+	template = strings.TrimPrefix(template, BasePath)
+	raw, ok := document.Paths[template][strings.ToLower(r.Method)]
+	if !ok {
+		return nil
+	}
+	var operation operationBody
+	if json.Unmarshal(raw, &operation) != nil || operation.RequestBody == nil {
+		return nil
+	}
+	// Bodies have a single media type, plain JSON or a merge patch
+	for _, content := range operation.RequestBody.Content {
+		return content.Schema
+	}
+	return nil
+}
+
+// validate returns every place the value doesn't match the schema
+func validate(s schema, value interface{}, at string) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		s = document.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	}
+	// null decodes to the zero value, which the handlers validate
+	if value == nil {
+		return nil
+	}
+
+	var problems []string
+	switch s["type"] {
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return []string{at + " must be a string"}
+		}
+		if s["format"] == "date" {
+			if _, err := time.Parse("2006-01-02", text); err != nil {
+				problems = append(problems, at+" must be a date such as 2023-09-06")
+			}
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return []string{at + " must be a whole number"}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{at + " must be a number"}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{at + " must be true or false"}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{at + " must be an array"}
+		}
+		if length, ok := s["minItems"].(float64); ok && len(items) < int(length) {
+			problems = append(problems, fmt.Sprintf("%s must have at least %d items", at, int(length)))
+		}
+		if length, ok := s["maxItems"].(float64); ok && len(items) > int(length) {
+			problems = append(problems, fmt.Sprintf("%s must have at most %d items", at, int(length)))
+		}
+		itemSchema, _ := s["items"].(schema)
+		for i, item := range items {
+			problems = append(problems, validate(itemSchema, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "object":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{at + " must be an object"}
+		}
+		properties, _ := s["properties"].(schema)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := properties[name].(schema); ok {
+				problems = append(problems, validate(property, fields[name], at+"."+name)...)
+				continue
+			}
+			switch extra := s["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					problems = append(problems, at+"."+name+" is not a known field")
+				}
+			case schema:
+				problems = append(problems, validate(extra, fields[name], at+"."+name)...)
+			}
+		}
+	}
+	return problems
+}
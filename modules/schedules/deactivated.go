@@ -0,0 +1,97 @@
+package schedules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// OrphanedSection is a section of a draft schedule taught by a deactivated user
+type OrphanedSection struct {
+	Year      int    `json:"year"`
+	Term      string `json:"term"`
+	Course    string `json:"course"`
+	Section   string `json:"section"`
+	Professor string `json:"professor"`
+	Username  string `json:"username"`
+}
+
+// OrphanedSections is the report of the sections taught by deactivated users
+type OrphanedSections struct {
+	Sections []OrphanedSection `json:"sections"`
+}
+
+// FindOrphanedSections returns the sections of the schedules taught by one of the users. A
+// section names its professor by name, or by username if the name wasn't known.
+func FindOrphanedSections(schedule_list []Schedule, user_list []users.User) []OrphanedSection {
+	usernames := map[string]string{}
+	for _, user := range user_list {
+		usernames[user.Username] = user.Username
+		if user.Name != "" {
+			usernames[user.Name] = user.Username
+		}
+	}
+
+	sections := []OrphanedSection{}
+	for _, schedule := range schedule_list {
+		for _, term := range schedule.Terms {
+			for _, offering := range term.Courses {
+				for _, section := range offering.Sections {
+					username, ok := usernames[section.Professor]
+					if !ok {
+						continue
+					}
+					sections = append(sections, OrphanedSection{
+						Year:      schedule.Year,
+						Term:      term.Term,
+						Course:    offering.Course,
+						Section:   section.Num,
+						Professor: section.Professor,
+						Username:  username,
+					})
+				}
+			}
+		}
+	}
+	return sections
+}
+
+// GetOrphanedSections - lists the sections of draft schedules still assigned to deactivated users
+func GetOrphanedSections(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, users_coll *mongo.Collection) {
+	logger.Info("GetOrphanedSections function called.")
+
+	var user_list []users.User
+	cursor, err := users_coll.Find(context.TODO(), bson.M{"deactivated_at": bson.M{"$ne": nil}})
+	if err == nil {
+		err = cursor.All(context.TODO(), &user_list)
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving deactivated users: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving deactivated users.")
+		return
+	}
+
+	var schedule_list []Schedule
+	if len(user_list) > 0 {
+		cursor, err = collection.Find(context.TODO(), bson.M{})
+		if err == nil {
+			err = cursor.All(context.TODO(), &schedule_list)
+		}
+		if err != nil {
+			logger.Error(fmt.Errorf("Error retrieving schedules: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving schedules.")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(OrphanedSections{Sections: FindOrphanedSections(schedule_list, user_list)})
+}
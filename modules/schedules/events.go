@@ -0,0 +1,101 @@
+package schedules
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+)
+
+// section identifies a section of a schedule
+type section struct {
+	Term   string
+	Course string
+	Num    string
+}
+
+// professorsOf returns the professors teaching a section of the schedule
+func professorsOf(schedule Schedule) []string {
+	seen := map[string]bool{}
+	professors := []string{}
+	for _, professor := range sectionProfessors(schedule) {
+		if professor != "" && !seen[professor] {
+			seen[professor] = true
+			professors = append(professors, professor)
+		}
+	}
+	sort.Strings(professors)
+	return professors
+}
+
+// sectionProfessors returns the professor of every section of the schedule
+func sectionProfessors(schedule Schedule) map[section]string {
+	professors := map[section]string{}
+	for _, term := range schedule.Terms {
+		for _, offering := range term.Courses {
+			for _, class := range offering.Sections {
+				professors[section{Term: term.Term, Course: offering.Course, Num: class.Num}] = class.Professor
+			}
+		}
+	}
+	return professors
+}
+
+// Reassignments returns a SectionReassigned event for every section whose professor differs
+// between the two versions of the schedule, including sections added or removed
+func Reassignments(before Schedule, after Schedule) []events.Event {
+	return reassignments(sectionProfessors(before), after)
+}
+
+// reassignments compares the professors of the sections before an update with the schedule after
+func reassignments(old map[section]string, after Schedule) []events.Event {
+	changed := sectionProfessors(after)
+	for key := range old {
+		if _, ok := changed[key]; !ok {
+			changed[key] = ""
+		}
+	}
+
+	reassigned := []events.Event{}
+	for key, to := range changed {
+		from := old[key]
+		if from == to {
+			continue
+		}
+		event := events.Event{
+			Type:    events.SectionReassigned,
+			Year:    after.Year,
+			Term:    key.Term,
+			Course:  key.Course,
+			Section: key.Num,
+			From:    from,
+			To:      to,
+		}
+		for _, professor := range []string{from, to} {
+			if professor != "" {
+				event.Professors = append(event.Professors, professor)
+			}
+		}
+		reassigned = append(reassigned, event)
+	}
+	// Maps are iterated in random order
+	sort.Slice(reassigned, func(i, j int) bool {
+		a, b := reassigned[i], reassigned[j]
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		if a.Course != b.Course {
+			return a.Course < b.Course
+		}
+		return a.Section < b.Section
+	})
+	return reassigned
+}
+
+// publishReassignments tells the subscribers about every section reassigned by an update, given
+// the professors of the sections before it
+func publishReassignments(ctx context.Context, before map[section]string, after Schedule) {
+	for _, event := range reassignments(before, after) {
+		events.Publish(ctx, event)
+	}
+}
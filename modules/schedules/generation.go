@@ -0,0 +1,261 @@
+package schedules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/blocks"
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// Errors returned by Generate and Approve, so callers can decide how to report them
+var (
+	ErrInvalidTerm      = errors.New("invalid term")
+	ErrInvalidYear      = errors.New("invalid year")
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrAlgorithms       = errors.New("error generating schedule with Algs 1")
+)
+
+// maxAlgorithmResponseBytes caps how much of a response from the algorithm services is read, a
+// longer one is cut off and fails to decode
+const maxAlgorithmResponseBytes = 32 << 20
+
+// checkTerm returns ErrInvalidTerm unless the term is configured for the year in the terms collection
+func checkTerm(ctx context.Context, terms_coll *mongo.Collection, year int, term string) error {
+	err := terms.Check(ctx, terms_coll, year, term)
+	if errors.Is(err, terms.ErrUnknownTerm) {
+		return ErrInvalidTerm
+	}
+	if err != nil {
+		return fmt.Errorf("error checking term: %w", err)
+	}
+	return nil
+}
+
+// Generate - builds the Algs 2 and Algs 1 requests for the year and term, stores the
+// generated schedule in the drafts collection and returns it
+func Generate(ctx context.Context, year string, term string, draft_schedules *mongo.Collection, users_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, algs1_api string, algs2_api string) (Schedule, error) {
+	generationJobs.Add(1)
+	defer generationJobs.Done()
+
+	year_int, err := strconv.Atoi(year)
+	if err != nil {
+		return Schedule{}, ErrInvalidYear
+	}
+	// The term is checked, requested and stored under its code
+	term = terms.Code(term)
+
+	// Check if passed term is valid
+	err = checkTerm(ctx, terms_coll, year_int, term)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	// Retrieve the courses offered in the term
+	var courses_list []courses.Course
+	cursor, err := courses_coll.Find(ctx, bson.M{"terms_offered": bson.M{"$regex": term}})
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error retrieving courses: %w", err)
+	}
+	err = cursor.All(ctx, &courses_list)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error decoding courses: %w", err)
+	}
+
+	// Ask Algs 2 for the capacity estimates, falling back to random estimates if it fails
+	capacity := requestCapacities(year, term, courses_list, algs2_api)
+	final_course := createCoursesArray(courses_list, capacity)
+
+	var users_list []users.User
+	// Deactivated users can't be given sections
+	cursor, err = users_coll.Find(ctx, users.Active)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error retrieving users: %w", err)
+	}
+	err = cursor.All(ctx, &users_list)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error decoding users: %w", err)
+	}
+
+	var classrooms_list []classrooms.Classroom
+	cursor, err = classrooms_coll.Find(ctx, bson.M{})
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error retrieving classrooms: %w", err)
+	}
+	err = cursor.All(ctx, &classrooms_list)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error decoding classrooms: %w", err)
+	}
+
+	// Courses in the same block should not be scheduled at the same time
+	term_blocks, err := blocks.ForTerm(ctx, blocks_coll, term)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error retrieving blocks: %w", err)
+	}
+
+	// Create Algs 1 Request
+	var new_algs1_request Algs1_Request
+	new_algs1_request.Year = year
+	new_algs1_request.Term = term
+	new_algs1_request.Professors = users_list
+	new_algs1_request.Courses = final_course
+	new_algs1_request.Classrooms = classrooms_list
+	new_algs1_request.Blocks = term_blocks
+
+	temp_schedule, err := requestSchedule(ctx, new_algs1_request, algs1_api)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	// Make the final schedule JSON
+	new_schedule := createScheduleJSON(year_int, term, temp_schedule)
+
+	// Algs 1 is asked to respect the blocks, report any clashes it could not avoid
+	clashes := FindClashes(new_schedule.Terms[0], term_blocks)
+	if len(clashes) > 0 {
+		logger.Warning(fmt.Sprintf("Generated schedule %s %s has %d block clashes", year, term, len(clashes)))
+	}
+
+	// Store the schedule in the MongoDB collection
+	_, err = draft_schedules.InsertOne(ctx, new_schedule)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("error inserting schedule into collection: %w", err)
+	}
+
+	events.Publish(ctx, events.Event{Type: events.ScheduleGenerated, Year: year_int, Term: term, Professors: professorsOf(new_schedule)})
+
+	return new_schedule, nil
+}
+
+// requestCapacities - asks Algs 2 for enrollment estimates, an empty Capacity means
+// createCoursesArray will pick random estimates instead
+// TODO: Still waiting for Algs 2 to have proper API response
+func requestCapacities(year string, term string, courses_list []courses.Course, algs2_api string) Capacity {
+	var new_algs2_request Algs2_Request
+	new_algs2_request.Year = year
+	new_algs2_request.Term = term
+	new_algs2_request.Courses = courses_list
+
+	// add course field
+	for i := range new_algs2_request.Courses {
+		new_algs2_request.Courses[i].SetCourse()
+	}
+
+	var capacity Capacity
+	algs2RequestBody, _ := json.Marshal(new_algs2_request)
+	algs2Res, err := http.Post(algs2_api, "application/json", bytes.NewBuffer(algs2RequestBody))
+	if err != nil {
+		logger.Error(fmt.Errorf("Error sending Algs 2 request: "+err.Error()), http.StatusInternalServerError)
+		return capacity
+	}
+	defer algs2Res.Body.Close()
+
+	if algs2Res.StatusCode != http.StatusOK {
+		logger.Warning("Algs 2 responded with status " + strconv.Itoa(algs2Res.StatusCode) + ", using random estimates")
+		return capacity
+	}
+
+	err = json.NewDecoder(io.LimitReader(algs2Res.Body, maxAlgorithmResponseBytes)).Decode(&capacity)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error trying to parse Algs 2 response body: "+err.Error()), http.StatusInternalServerError)
+		return Capacity{}
+	}
+	return capacity
+}
+
+// requestSchedule - sends the request to Algs 1 and decodes the generated schedule
+func requestSchedule(ctx context.Context, request Algs1_Request, algs1_api string) (Algs1_Schedule, error) {
+	var temp_schedule Algs1_Schedule
+
+	algs1RequestBody, err := json.Marshal(request)
+	if err != nil {
+		return temp_schedule, fmt.Errorf("error encoding Algs 1 request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, algs1_api, bytes.NewBuffer(algs1RequestBody))
+	if err != nil {
+		return temp_schedule, fmt.Errorf("error creating Algs 1 request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	logger.Info("Request sent to Algs 1 ...")
+	algs1Res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return temp_schedule, fmt.Errorf("%w: %s", ErrAlgorithms, err.Error())
+	}
+	defer algs1Res.Body.Close()
+
+	if algs1Res.StatusCode != http.StatusOK {
+		return temp_schedule, fmt.Errorf("%w: responded with status %d", ErrAlgorithms, algs1Res.StatusCode)
+	}
+
+	err = json.NewDecoder(io.LimitReader(algs1Res.Body, maxAlgorithmResponseBytes)).Decode(&temp_schedule)
+	if err != nil {
+		return temp_schedule, fmt.Errorf("%w: error parsing generated schedule: %s", ErrAlgorithms, err.Error())
+	}
+	return temp_schedule, nil
+}
+
+// Approve - moves the schedule for the year and term from the drafts collection to the
+// previous_schedules collection
+func Approve(ctx context.Context, year int, term string, draftsCollection *mongo.Collection, previousSchedulesCollection *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection) error {
+	term = terms.Code(term)
+
+	// Check if passed term is valid
+	err := checkTerm(ctx, terms_coll, year, term)
+	if err != nil {
+		return err
+	}
+
+	// Prepare the filter to find the specific schedule
+	filter := bson.M{
+		"year":       year,
+		"terms.term": term,
+	}
+
+	// Find the schedule in the "draft_schedule" collection
+	var foundSchedule Schedule
+	err = draftsCollection.FindOne(ctx, filter).Decode(&foundSchedule)
+	if err == mongo.ErrNoDocuments {
+		return ErrScheduleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find schedule in drafts collection: %w", err)
+	}
+
+	// A schedule that breaks a hard constraint can't be approved
+	_, err = validateSchedule(ctx, foundSchedule, blocks_coll, courses_coll, classrooms_coll)
+	if err != nil {
+		return err
+	}
+
+	// Insert the found schedule into the "previous_schedules" collection
+	_, err = previousSchedulesCollection.InsertOne(ctx, foundSchedule)
+	if err != nil {
+		return fmt.Errorf("failed to insert schedule into previous_schedules collection: %w", err)
+	}
+
+	// Delete the schedule from the "draft_schedule" collection
+	_, err = draftsCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete from drafts collection: %w", err)
+	}
+
+	events.Publish(ctx, events.Event{Type: events.ScheduleApproved, Year: year, Term: term, Professors: professorsOf(foundSchedule)})
+
+	return nil
+}
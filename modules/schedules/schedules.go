@@ -1,7 +1,6 @@
 package schedules
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,20 +9,28 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/blocks"
 	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
 	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
 	"github.com/SENG-499-Company2-B01/Backend/modules/users"
 )
 
 // Schedule represents a schedule entity
 type Schedule struct {
-	Year  int    `json:"year"`
-	Terms []Term `json:"terms"`
+	Year    int    `json:"year"`
+	Terms   []Term `json:"terms"`
+	Version int64  `json:"version"`
 }
 
 type Algs1_Schedule struct {
@@ -63,6 +70,7 @@ type Algs1_Request struct {
 	Professors []users.User            `json:"professors"`
 	Courses    []CoursesWithCapacities `json:"courses"`
 	Classrooms []classrooms.Classroom  `json:"classrooms"`
+	Blocks     []blocks.Block          `json:"blocks"`
 }
 
 type Algs2_Request struct {
@@ -77,35 +85,51 @@ type Estimate struct {
 }
 
 type CoursesWithCapacities struct {
-	Course        string     `json:"course" bson:"course"`
-	Peng          bool       `json:"peng" bson:"peng"`
-	Prerequisites [][]string `json:"prerequisites" bson:"prerequisites"`
-	CoRequisites  [][]string `json:"corequisites" bson:"corequisites"`
-	Pre_enroll    int        `json:"pre_enroll" bson:"pre_enroll"`
-	Min_enroll    int        `json:"min_enroll" bson:"min_enroll"`
-	Hours         [3]int     `json:"hours" bson:"hours"`
+	Course            string     `json:"course" bson:"course"`
+	Peng              bool       `json:"peng" bson:"peng"`
+	Prerequisites     [][]string `json:"prerequisites" bson:"prerequisites"`
+	CoRequisites      [][]string `json:"corequisites" bson:"corequisites"`
+	Pre_enroll        int        `json:"pre_enroll" bson:"pre_enroll"`
+	Min_enroll        int        `json:"min_enroll" bson:"min_enroll"`
+	Max_enroll        int        `json:"max_enroll" bson:"max_enroll"`
+	Sections          int        `json:"sections" bson:"sections"`
+	Hours             [3]float64 `json:"hours" bson:"hours"`
+	Required_features []string   `json:"required_features" bson:"required_features"`
+	Room_type         string     `json:"room_type" bson:"room_type"`
 }
 
 type Capacity struct {
 	Estimates []Estimate `json:"estimates"`
 }
 
+// generationJobs tracks the schedule generations currently in progress
+var generationJobs sync.WaitGroup
+
+// WaitForGenerationJobs blocks until every in-progress schedule generation has finished,
+// or returns the context's error if it is done first
+func WaitForGenerationJobs(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		generationJobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func createCoursesArray(term_courses []courses.Course, pred_capacities Capacity) []CoursesWithCapacities {
 
-	hours := [3]int{3, 0, 0}
 	var updated_courses []CoursesWithCapacities
 	for i := 0; i < len(term_courses); i++ {
 
 		course_name := term_courses[i].ShortHand
 		if len(pred_capacities.Estimates) == 0 {
-			var new_course CoursesWithCapacities
-			new_course.Course = course_name
-			new_course.Peng = false
-			new_course.Prerequisites = term_courses[i].Prerequisites
-			new_course.CoRequisites = term_courses[i].CoRequisites
-			new_course.Pre_enroll = rand.Intn(120-80) + 80
-			new_course.Min_enroll = 5
-			new_course.Hours = hours
+			new_course := newCourseWithCapacity(term_courses[i], rand.Intn(120-80)+80)
 			updated_courses = append(updated_courses, new_course)
 
 		} else {
@@ -114,14 +138,7 @@ func createCoursesArray(term_courses []courses.Course, pred_capacities Capacity)
 				pred_course_name := pred_capacities.Estimates[j].Course
 				if course_name == pred_course_name {
 
-					var new_course CoursesWithCapacities
-					new_course.Course = course_name
-					new_course.Peng = false
-					new_course.Prerequisites = term_courses[i].Prerequisites
-					new_course.CoRequisites = term_courses[i].CoRequisites
-					new_course.Pre_enroll = pred_capacities.Estimates[j].Estimate
-					new_course.Min_enroll = 5
-					new_course.Hours = hours
+					new_course := newCourseWithCapacity(term_courses[i], pred_capacities.Estimates[j].Estimate)
 					updated_courses = append(updated_courses, new_course)
 					break
 				}
@@ -133,10 +150,35 @@ func createCoursesArray(term_courses []courses.Course, pred_capacities Capacity)
 	return updated_courses
 }
 
+// newCourseWithCapacity - builds the Algs 1 course from the stored course and its enrollment estimate
+func newCourseWithCapacity(course courses.Course, estimate int) CoursesWithCapacities {
+	course.ApplyDefaults()
+
+	// The estimate can't go over the enrollment limit, 0 means the course has no limit
+	if course.MaxEnroll > 0 && estimate > course.MaxEnroll {
+		estimate = course.MaxEnroll
+	}
+
+	var new_course CoursesWithCapacities
+	new_course.Course = course.ShortHand
+	new_course.Peng = course.Peng
+	new_course.Prerequisites = course.Prerequisites
+	new_course.CoRequisites = course.CoRequisites
+	new_course.Pre_enroll = estimate
+	new_course.Min_enroll = course.MinEnroll
+	new_course.Max_enroll = course.MaxEnroll
+	new_course.Sections = course.Sections
+	new_course.Hours = course.Hours.Array()
+	new_course.Required_features = course.RequiredFeatures
+	new_course.Room_type = course.RoomType
+	return new_course
+}
+
 func createScheduleJSON(year int, term string, algs1_sched Algs1_Schedule) Schedule {
 
 	var final_schedule Schedule
 	final_schedule.Year = year
+	final_schedule.Version = 1
 
 	var terms []Term
 	var current_term Term
@@ -149,207 +191,27 @@ func createScheduleJSON(year int, term string, algs1_sched Algs1_Schedule) Sched
 }
 
 // GenerateSchedule - Generates a new schedule
-// TODO: Still waiting for Algs 2 to have proper API response
-func GenerateSchedule(w http.ResponseWriter, r *http.Request, draft_schedules *mongo.Collection, users_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection, algs1_api string, algs2_api string) {
+func GenerateSchedule(w http.ResponseWriter, r *http.Request, draft_schedules *mongo.Collection, users_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, algs1_api string, algs2_api string) {
 	logger.Info("GenerateSchedule function called.")
 
 	// Extract the year and term values from the URL path
-	path := strings.Split(r.URL.Path, "/")
-	if len(path) < 4 {
-		// If there is an error parsing the url path,
-		// log the error and return a bad request response
-		logger.Error(fmt.Errorf("invalid URL path"), http.StatusBadRequest)
-		http.Error(w, "Invalid URL path.", http.StatusBadRequest)
-		return
-	}
-
-	// Extract the year and term from path
-	year := path[2]
-	term := path[3]
-
-	// Check if passed term is valid
-	if strings.ToLower(term) != "fall" && strings.ToLower(term) != "spring" && strings.ToLower(term) != "summer" {
-		logger.Error(fmt.Errorf("invalid term for generating schedule"), http.StatusBadRequest)
-		http.Error(w, "Invalid Term for Generating Schedule", http.StatusBadRequest)
-		return
-	}
-
-	var courses_list []courses.Course
-
-	// Retrieve all documents from the MongoDB collection
-	cursor1, err := courses_coll.Find(context.TODO(), bson.M{"terms_offered": bson.M{"$regex": term}})
-	if err != nil {
-		logger.Error(fmt.Errorf("Error retrieving users: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving users.", http.StatusInternalServerError)
-		return
-	}
-	defer cursor1.Close(context.TODO())
-
-	// Iterate through the cursor and decode each document into a User struct
-	for cursor1.Next(context.TODO()) {
-		var course courses.Course
-		err := cursor1.Decode(&course)
-		if err != nil {
-			logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-			return
-		}
-		courses_list = append(courses_list, course)
-	}
-
-	// Check for any errors during cursor iteration
-	if err := cursor1.Err(); err != nil {
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-		return
-	}
-
-	// Create Algs 2 Request
-	var new_algs2_request Algs2_Request
-	new_algs2_request.Year = year
-	new_algs2_request.Term = strings.ToLower(term)
-	new_algs2_request.Courses = courses_list
-
-	// add course field
-	for i := range new_algs2_request.Courses {
-		new_algs2_request.Courses[i].SetCourse()
-	}
-
-	algs2RequestBody, _ := json.Marshal(new_algs2_request)
-	algs2Payload := []byte(algs2RequestBody)
-	algs2Req, _ := http.Post(algs2_api, "application/json", bytes.NewBuffer(algs2Payload))
-
-	// Check the response status code and populate the capacity array
-	var capacity Capacity
-	if algs2Req.StatusCode == http.StatusOK { // Response status is 200 (OK)
-		// Parse the response body into the capacity variable
-		decoder := json.NewDecoder(algs2Req.Body)
-		err := decoder.Decode(&capacity)
-
-		if err != nil {
-
-			//fmt.Println("Error parsing Algs 2 response")
-			// Handle error in parsing response body
-			logger.Error(fmt.Errorf("Error trying to parse response body: "+err.Error()), http.StatusInternalServerError)
-			// http.Error(w, "Error trying to parse response body.", http.StatusInternalServerError)
-
-			// Construct an empty capacity array
-			//capacity = capacity
-		}
+	vars := mux.Vars(r)
+	year := vars["year"]
+	term := terms.Code(vars["term"])
 
-	} else { // Response status is not 200 (OK)
-		// Construct an empty capacity array
-		// create a random number between 80 and 100 for each course.
-		fmt.Println("NON 200 Status for Algs 2")
-		//capacity = capacity
-	}
-
-	final_course := createCoursesArray(courses_list, capacity)
-
-	var users_list []users.User
-	var classrooms_list []classrooms.Classroom
-
-	cursor2, err := users_coll.Find(context.TODO(), bson.M{})
-	if err != nil {
-		logger.Error(fmt.Errorf("Error retrieving users: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving users.", http.StatusInternalServerError)
-		return
-	}
-	defer cursor2.Close(context.TODO())
-
-	// Iterate through the cursor and decode each document into a User struct
-	for cursor2.Next(context.TODO()) {
-		var user users.User
-		err := cursor2.Decode(&user)
-		if err != nil {
-			logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-			return
-		}
-		users_list = append(users_list, user)
-	}
-
-	// Check for any errors during cursor iteration
-	if err := cursor2.Err(); err != nil {
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-		return
-	}
-
-	// Retrieve all documents from the MongoDB collection
-	cursor3, err := classrooms_coll.Find(context.TODO(), bson.M{})
+	new_schedule, err := Generate(r.Context(), year, term, draft_schedules, users_coll, courses_coll, classrooms_coll, terms_coll, blocks_coll, algs1_api, algs2_api)
 	if err != nil {
-		logger.Error(fmt.Errorf("Error retrieving classrooms: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving classrooms.", http.StatusInternalServerError)
-		return
-	}
-	defer cursor3.Close(context.TODO())
-
-	// Iterate through the cursor and decode each document into a User struct
-	for cursor3.Next(context.TODO()) {
-		var classroom classrooms.Classroom
-		err := cursor3.Decode(&classroom)
-		if err != nil {
-			logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-			return
+		switch {
+		case errors.Is(err, ErrInvalidYear):
+			logger.Error(fmt.Errorf("invalid year for generating schedule"), http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Year for Generating Schedule")
+		case errors.Is(err, ErrInvalidTerm):
+			logger.Error(fmt.Errorf("invalid term for generating schedule"), http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Term for Generating Schedule")
+		default:
+			logger.Error(fmt.Errorf("Error generating schedule: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error generating schedule.")
 		}
-		classrooms_list = append(classrooms_list, classroom)
-	}
-
-	// Check for any errors during cursor iteration
-	if err := cursor3.Err(); err != nil {
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
-		return
-	}
-
-	// Create Algs 1 Request
-	var new_algs1_request Algs1_Request
-	new_algs1_request.Year = year
-	new_algs1_request.Term = term
-	new_algs1_request.Professors = users_list
-	new_algs1_request.Courses = final_course
-	new_algs1_request.Classrooms = classrooms_list
-
-	algs1RequestBody, _ := json.Marshal(new_algs1_request)
-
-	fmt.Println(string(algs1RequestBody))
-	algs1Payload := []byte(algs1RequestBody)
-	algs1Req, err := http.Post(algs1_api, "application/json", bytes.NewBuffer(algs1Payload))
-
-	if err != nil {
-		logger.Error(fmt.Errorf("Error sending Algs 1 request: "+err.Error()), http.StatusInternalServerError)
-	}
-
-	fmt.Println("Request sent to Algs 1 ...")
-
-	var temp_schedule Algs1_Schedule
-	var final_error error
-	if algs1Req.StatusCode == http.StatusOK {
-		fmt.Println("Valid request to Algs 1 ...")
-		final_error = json.NewDecoder(algs1Req.Body).Decode(&temp_schedule)
-	} else {
-		fmt.Println("Algs 1 response ...", algs1Req.StatusCode)
-		final_error = errors.New(algs1Req.Body.Close().Error())
-	}
-
-	if final_error != nil {
-		logger.Error(fmt.Errorf("Error parsing generated schedule: "+final_error.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error parsing generated schedule.", http.StatusInternalServerError)
-		return
-	}
-
-	// Make the final schedule JSON
-	var new_schedule Schedule
-	year_int, _ := strconv.Atoi(year)
-	new_schedule = createScheduleJSON(year_int, term, temp_schedule)
-
-	// Store the schedule in the MongoDB collection
-	_, insertErr := draft_schedules.InsertOne(context.TODO(), new_schedule)
-	if insertErr != nil {
-		logger.Error(fmt.Errorf("Error inserting schedule into collection: "+insertErr.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error inserting schedule into collection.", http.StatusInternalServerError)
 		return
 	}
 
@@ -359,7 +221,7 @@ func GenerateSchedule(w http.ResponseWriter, r *http.Request, draft_schedules *m
 }
 
 // ApproveSchedule - removes schedule in draft collection and adds it to previous_schedules collection, approving it.
-func ApproveSchedule(w http.ResponseWriter, r *http.Request, draftsCollection *mongo.Collection, previousSchedulesCollection *mongo.Collection) {
+func ApproveSchedule(w http.ResponseWriter, r *http.Request, draftsCollection *mongo.Collection, previousSchedulesCollection *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection) {
 	logger.Info("ApproveSchedule function called.")
 
 	// Extract the year and term from the request body
@@ -367,45 +229,25 @@ func ApproveSchedule(w http.ResponseWriter, r *http.Request, draftsCollection *m
 	err := json.NewDecoder(r.Body).Decode(&requestBody)
 	if err != nil {
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
-		return
-	}
-
-	// Check if passed term is valid
-	if strings.ToLower(requestBody.Term) != "fall" && strings.ToLower(requestBody.Term) != "spring" && strings.ToLower(requestBody.Term) != "summer" {
-		logger.Error(fmt.Errorf("invalid term for generating schedule"), http.StatusBadRequest)
-		http.Error(w, "Invalid Term for Generating Schedule", http.StatusBadRequest)
-		return
-	}
-
-	// Prepare the filter to find the specific schedule
-	filter := bson.M{
-		"year":       requestBody.Year,
-		"terms.term": requestBody.Term,
-	}
-
-	// Find the schedule in the "draft_schedule" collection
-	var foundSchedule Schedule
-	err = draftsCollection.FindOne(context.TODO(), filter).Decode(&foundSchedule)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to find schedule in drafts collection"), http.StatusInternalServerError)
-		http.Error(w, "Failed to find schedule.", http.StatusInternalServerError)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
 		return
 	}
 
-	// Insert the found schedule into the "previous_schedules" collection
-	_, err = previousSchedulesCollection.InsertOne(context.TODO(), foundSchedule)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to insert schedule into previous_schedules collection"), http.StatusInternalServerError)
-		http.Error(w, "Failed to insert schedule.", http.StatusInternalServerError)
-		return
-	}
+	requestBody.Term = terms.Code(requestBody.Term)
 
-	// Delete the schedule from the "draft_schedule" collection
-	_, err = draftsCollection.DeleteOne(context.TODO(), filter)
+	err = Approve(r.Context(), requestBody.Year, requestBody.Term, draftsCollection, previousSchedulesCollection, terms_coll, blocks_coll, courses_coll, classrooms_coll)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to delete from drafts collection"), http.StatusInternalServerError)
-		http.Error(w, "Failed to delete schedule.", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, ErrInvalidTerm):
+			logger.Error(fmt.Errorf("invalid term for generating schedule"), http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Term for Generating Schedule")
+		case errors.Is(err, ErrHardClashes), errors.Is(err, ErrRoomProblems):
+			logger.Error(fmt.Errorf("Error approving schedule: "+err.Error()), http.StatusConflict)
+			apierror.Write(w, r, http.StatusConflict, apierror.ConstraintViolation, "Schedule breaks a hard constraint, check /schedules/"+strconv.Itoa(requestBody.Year)+"/"+requestBody.Term+"/validation.")
+		default:
+			logger.Error(fmt.Errorf("Error approving schedule: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Failed to approve schedule.")
+		}
 		return
 	}
 
@@ -417,80 +259,62 @@ func ApproveSchedule(w http.ResponseWriter, r *http.Request, draftsCollection *m
 	// logger.Info("ApproveSchedule function completed.")
 }
 
+// scheduleList - the filters and sort keys of GET /schedules
+var scheduleList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "year", Field: "year", Kind: helper.Int},
+		{Param: "term", Field: "terms.term", Kind: helper.Exact},
+	},
+	Sorts:       map[string]string{"year": "year"},
+	DefaultSort: "year",
+}
+
 // GetSchedules retrieves all schedules from the MongoDB collection
 func GetSchedules(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("GetSchedules function called.")
 
-	// Define an empty slice to store the schedules
-	var schedules []Schedule
-
-	// Retrieve all documents from the MongoDB collection
-	cursor, err := collection.Find(context.TODO(), bson.M{})
+	query, err := helper.ParseListQuery(r, scheduleList)
 	if err != nil {
-		// If there is an error retrieving schedules,
-		// log the error and return an internal server error response
-		logger.Error(fmt.Errorf("Error retrieving schedules: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving schedules.", http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	// Iterate through the cursor and decode each document into a Schedule struct
-	for cursor.Next(context.TODO()) {
-		var schedule Schedule
-		err := cursor.Decode(&schedule)
-		if err != nil {
-			// If there is an error decoding a schedule document,
-			// log the error and return an internal server error response
-			logger.Error(fmt.Errorf("Error decoding schedule: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error decoding schedule.", http.StatusInternalServerError)
-			return
-		}
-		schedules = append(schedules, schedule)
-	}
 
-	// Check for any errors during cursor iteration
-	if err := cursor.Err(); err != nil {
-		// If there is an error iterating through the cursor,
-		// log the error and return an internal server error response
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
+	page, err := helper.FindPage[Schedule](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving schedules: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving schedules.")
 		return
 	}
 
-	// Send a response with the retrieved schedules
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(schedules)
-
-	// Uncomment the follow line for debugging
-	// logger.Info("GetSchedules function completed.")
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetSchedule retrieves a schedule by year
-func GetSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+func GetSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, terms_coll *mongo.Collection) {
 	logger.Info("GetSchedule function called.")
 
-	// Parse the URL parameters
-	params := strings.Split(r.URL.Path, "/")
-	if len(params) < 4 {
-		logger.Error(fmt.Errorf("invalid URL path"), http.StatusBadRequest)
-		http.Error(w, "Invalid URL format", http.StatusBadRequest)
-		return
-	}
-
 	// Extract year and term from the URL
-	year, err := strconv.Atoi(params[2])
+	vars := mux.Vars(r)
+	year, err := strconv.Atoi(vars["year"])
 	if err != nil {
 		logger.Error(fmt.Errorf("invalid year"), http.StatusBadRequest)
-		http.Error(w, "Invalid year", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid year")
 		return
 	}
-	term := params[3]
+	term := terms.Code(vars["term"])
 
 	// Check if passed term is valid
-	if strings.ToLower(term) != "fall" && strings.ToLower(term) != "spring" && strings.ToLower(term) != "summer" {
+	err = checkTerm(r.Context(), terms_coll, year, term)
+	if errors.Is(err, ErrInvalidTerm) {
 		logger.Error(fmt.Errorf("invalid term for generating schedule"), http.StatusBadRequest)
-		http.Error(w, "Invalid Term for Generating Schedule", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Term for Generating Schedule")
+		return
+	}
+	if err != nil {
+		logger.Error(err, http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking term.")
 		return
 	}
 
@@ -505,13 +329,18 @@ func GetSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Colle
 	err = collection.FindOne(context.TODO(), filter).Decode(&schedule)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			http.Error(w, "Schedule not found", http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Schedule not found")
 		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Internal server error")
 		}
 		return
 	}
 
+	// The client's copy is still current, schedules are large
+	if etag.NotModified(w, r, schedule.Version) {
+		return
+	}
+
 	// Send a response with the retrieved schedules
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(schedule)
@@ -520,31 +349,61 @@ func GetSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Colle
 	// logger.Info("GetSchedule function completed.")
 }
 
-// UpdateSchedule handles updating an existing schedule
-func UpdateSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+// scheduleFields - the fields a merge patch may change, arrays are replaced as a whole
+var scheduleFields = []string{"terms"}
+
+// UpdateSchedule replaces a draft schedule with the request body, the year may be left out
+func UpdateSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection) {
 	logger.Info("UpdateSchedule function called.")
 
-	// Parse the URL parameters
-	params := strings.Split(r.URL.Path, "/")
-	if len(params) < 4 {
-		logger.Error(fmt.Errorf("invalid URL path"), http.StatusBadRequest)
-		http.Error(w, "Invalid URL format", http.StatusBadRequest)
-		return
+	updateSchedule(w, r, collection, terms_coll, blocks_coll, courses_coll, classrooms_coll, func(schedule *Schedule) ([]string, error) {
+		*schedule = Schedule{Year: schedule.Year}
+		return nil, helper.DecodeStrict(r.Body, schedule)
+	})
+}
+
+// PatchSchedule applies a JSON merge patch from the request body to a draft schedule
+func PatchSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection) {
+	logger.Info("PatchSchedule function called.")
+
+	updateSchedule(w, r, collection, terms_coll, blocks_coll, courses_coll, classrooms_coll, func(schedule *Schedule) ([]string, error) {
+		return helper.MergePatch(schedule, r.Body, scheduleFields)
+	})
+}
+
+// hasTerm reports whether the schedule has an entry for the term
+func hasTerm(schedule Schedule, term string) bool {
+	for _, scheduleTerm := range schedule.Terms {
+		if scheduleTerm.Term == term {
+			return true
+		}
 	}
+	return false
+}
 
+// updateSchedule applies a change from the request body to the stored schedule and saves it once
+// it is valid. The change returns the problems with the body, or an error if it can't be decoded.
+func updateSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, terms_coll *mongo.Collection, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection, change func(schedule *Schedule) ([]string, error)) {
 	// Extract year and term from the URL
-	year, err := strconv.Atoi(params[2])
+	vars := mux.Vars(r)
+	year, err := strconv.Atoi(vars["year"])
 	if err != nil {
 		logger.Error(fmt.Errorf("invalid year"), http.StatusBadRequest)
-		http.Error(w, "Invalid year", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid year")
 		return
 	}
-	term := params[3]
+	term := terms.Code(vars["term"])
 
 	// Check if passed term is valid
-	if strings.ToLower(term) != "fall" && strings.ToLower(term) != "spring" && strings.ToLower(term) != "summer" {
+	err = checkTerm(r.Context(), terms_coll, year, term)
+	if errors.Is(err, ErrInvalidTerm) {
 		logger.Error(fmt.Errorf("invalid term for generating schedule"), http.StatusBadRequest)
-		http.Error(w, "Invalid Term for Generating Schedule", http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid Term for Generating Schedule")
+		return
+	}
+	if err != nil {
+		logger.Error(err, http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking term.")
 		return
 	}
 
@@ -554,47 +413,91 @@ func UpdateSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Co
 		"terms.term": term,
 	}
 
-	exists, err := scheduleExists(filter, collection)
+	// Find the schedule being updated
+	var schedule Schedule
+	err = collection.FindOne(context.TODO(), filter).Decode(&schedule)
+	if err == mongo.ErrNoDocuments {
+		// If the year doesn't exist,
+		// return a not found response
+		logger.Error(fmt.Errorf("schedule does not exist"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Schedule does not exist.")
+		return
+	}
 	if err != nil {
 		// If there is an error querying the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error querying collection: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error querying collection.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error querying collection.")
 		return
 	}
-	if !exists {
-		// If the year doesn't exist,
-		// return a not found response
-		logger.Error(fmt.Errorf("schedule does not exist"), http.StatusInternalServerError)
-		http.Error(w, "Schedule does not exist.", http.StatusInternalServerError)
+
+	// The client must have read the current version
+	version := schedule.Version
+	if !etag.CheckIfMatch(w, r, version) {
 		return
 	}
 
-	// Parse request body into a map
-	var requestBody map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	// Apply the request body to the stored schedule, keeping what it was to find reassigned sections
+	before := sectionProfessors(schedule)
+	problems, err := change(&schedule)
 	if err != nil {
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.", helper.DecodeProblem(err))
+		return
+	}
+	if len(problems) == 0 && schedule.Year != year {
+		problems = append(problems, "year can't be changed")
+	}
+	// The draft is found by its terms, without this one it would no longer be at its URL
+	if len(problems) == 0 && !hasTerm(schedule, term) {
+		problems = append(problems, fmt.Sprintf("terms must include %s", term))
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid schedule: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid schedule.", apierror.Problems(problems)...)
 		return
 	}
 
-	// Construct the update query
-	update := bson.M{"$set": requestBody}
+	// Courses that share a hard block must not overlap and every section needs a suitable
+	// classroom, clashes in soft blocks are only reported
+	report, err := validateSchedule(r.Context(), schedule, blocks_coll, courses_coll, classrooms_coll)
+	if errors.Is(err, ErrHardClashes) || errors.Is(err, ErrRoomProblems) {
+		logger.Error(fmt.Errorf("Error updating schedule: "+err.Error()), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.ConstraintViolation, "Schedule breaks a hard constraint.", report)
+		return
+	}
+	if err != nil {
+		logger.Error(err, http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error validating schedule.")
+		return
+	}
+	if report.Soft > 0 {
+		logger.Warning(fmt.Sprintf("Schedule %d %s has %d soft block clashes", year, term, report.Soft))
+	}
+
+	// Store the whole schedule so updated documents are encoded the same way as generated ones
+	update := bson.M{"$set": bson.M{"year": schedule.Year, "terms": schedule.Terms, "version": version + 1}}
 
-	// Update the schedule in the MongoDB collection
-	_, err = collection.UpdateOne(context.TODO(), filter, update)
+	// Update the schedule in the MongoDB collection, unless someone else did since it was read
+	filter[etag.Field] = etag.Current(version)
+	res, err := collection.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		// If there is an error updating the schedule in the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error updating schedule: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error updating schedule.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating schedule.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		etag.Conflict(w, r)
 		return
 	}
+	publishReassignments(r.Context(), before, schedule)
 
 	// Send a response indicating successful schedule update
+	etag.Set(w, version+1)
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "Schedule updated successfully")
 
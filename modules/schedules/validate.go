@@ -0,0 +1,350 @@
+package schedules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/blocks"
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
+)
+
+// Errors returned when a schedule breaks a hard constraint
+var (
+	ErrHardClashes  = errors.New("schedule has clashes between courses of a hard block")
+	ErrRoomProblems = errors.New("schedule has sections in classrooms that don't suit them")
+)
+
+// Clash is a pair of sections of two courses in the same block that meet at the same time
+type Clash struct {
+	Term       string   `json:"term"`
+	Block      string   `json:"block"`
+	Constraint string   `json:"constraint"`
+	Course     string   `json:"course"`
+	Section    string   `json:"section"`
+	Other      string   `json:"other_course"`
+	OtherNum   string   `json:"other_section"`
+	Days       []string `json:"days"`
+	StartTime  string   `json:"start_time"`
+	EndTime    string   `json:"end_time"`
+}
+
+// RoomProblem is a section booked in a classroom that doesn't suit it
+type RoomProblem struct {
+	Term     string `json:"term"`
+	Course   string `json:"course"`
+	Section  string `json:"section"`
+	Building string `json:"building"`
+	Room     string `json:"room"`
+	Problem  string `json:"problem"`
+}
+
+// Report lists the problems found in a schedule. Room problems and clashes in hard blocks
+// count as hard, clashes in soft blocks count as soft.
+type Report struct {
+	Year         int           `json:"year"`
+	Term         string        `json:"term"`
+	Hard         int           `json:"hard"`
+	Soft         int           `json:"soft"`
+	Clashes      []Clash       `json:"clashes"`
+	RoomProblems []RoomProblem `json:"room_problems,omitempty"`
+}
+
+// FindClashes returns every pair of overlapping sections of two courses that share a block
+func FindClashes(term Term, term_blocks []blocks.Block) []Clash {
+	clashes := []Clash{}
+
+	offerings := make(map[string]CourseOffering, len(term.Courses))
+	for _, offering := range term.Courses {
+		offerings[offering.Course] = offering
+	}
+
+	for _, block := range term_blocks {
+		for i := 0; i < len(block.Courses); i++ {
+			first, ok := offerings[block.Courses[i]]
+			if !ok {
+				continue
+			}
+			for j := i + 1; j < len(block.Courses); j++ {
+				second, ok := offerings[block.Courses[j]]
+				if !ok {
+					continue
+				}
+				for _, a := range first.Sections {
+					for _, b := range second.Sections {
+						days, overlaps := overlap(a, b)
+						if !overlaps {
+							continue
+						}
+						clashes = append(clashes, Clash{
+							Term:       term.Term,
+							Block:      block.Code,
+							Constraint: block.Constraint,
+							Course:     first.Course,
+							Section:    a.Num,
+							Other:      second.Course,
+							OtherNum:   b.Num,
+							Days:       days,
+							StartTime:  a.StartTime,
+							EndTime:    a.EndTime,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(clashes, func(i, j int) bool {
+		return clashes[i].Constraint == blocks.Hard && clashes[j].Constraint != blocks.Hard
+	})
+	return clashes
+}
+
+// overlap reports whether two sections meet at the same time, and on which days
+func overlap(a Class, b Class) ([]string, bool) {
+	var days []string
+	for _, day := range a.Days {
+		for _, other := range b.Days {
+			if day == other {
+				days = append(days, day)
+			}
+		}
+	}
+	if len(days) == 0 {
+		return nil, false
+	}
+
+	aStart, aErr := parseClock(a.StartTime)
+	aEnd, aEndErr := parseClock(a.EndTime)
+	bStart, bErr := parseClock(b.StartTime)
+	bEnd, bEndErr := parseClock(b.EndTime)
+	if aErr != nil || aEndErr != nil || bErr != nil || bEndErr != nil {
+		// Sections without usable times can't be checked
+		return nil, false
+	}
+	return days, aStart < bEnd && bStart < aEnd
+}
+
+// parseClock parses a 24 hour "15:04" time into the duration since midnight
+func parseClock(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// FindRoomProblems returns every section whose classroom doesn't exist, is too small, is the
+// wrong type, lacks a feature the course needs or is blacked out at the section's time
+func FindRoomProblems(term Term, course_list map[string]courses.Course, classroom_list map[string]classrooms.Classroom) []RoomProblem {
+	problems := []RoomProblem{}
+
+	for _, offering := range term.Courses {
+		course := course_list[offering.Course]
+		for _, section := range offering.Sections {
+			// Sections without a room, such as online sections, have nothing to check
+			if section.Building == "" && section.Room == "" {
+				continue
+			}
+			report := func(problem string) {
+				problems = append(problems, RoomProblem{
+					Term:     term.Term,
+					Course:   offering.Course,
+					Section:  section.Num,
+					Building: section.Building,
+					Room:     section.Room,
+					Problem:  problem,
+				})
+			}
+
+			classroom, ok := classroom_list[classroomKey(section.Building, section.Room)]
+			if !ok {
+				report("classroom doesn't exist")
+				continue
+			}
+			if classroom.Capacity > 0 && section.NumSeats > classroom.Capacity {
+				report(fmt.Sprintf("%d seats don't fit in a classroom for %d", section.NumSeats, classroom.Capacity))
+			}
+			if course.RoomType != "" && course.RoomType != classroom.RoomType() {
+				report("course needs a " + course.RoomType + " room, not a " + classroom.RoomType() + " room")
+			}
+			if missing := classroom.MissingFeatures(course.RequiredFeatures); len(missing) > 0 {
+				report("classroom doesn't have " + strings.Join(missing, ", "))
+			}
+			for _, blackout := range classroom.Blackouts {
+				days, overlaps := overlap(section, Class{Days: blackout.Days, StartTime: blackout.StartTime, EndTime: blackout.EndTime})
+				if overlaps {
+					report(fmt.Sprintf("classroom is unavailable %s %s-%s", strings.Join(days, ""), blackout.StartTime, blackout.EndTime))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// classroomKey identifies a classroom by building and room
+func classroomKey(building string, room string) string {
+	return building + " " + room
+}
+
+// validateSchedule checks every term in the schedule against the blocks, courses and classrooms.
+// The report is returned with ErrHardClashes or ErrRoomProblems if a hard constraint is broken.
+func validateSchedule(ctx context.Context, schedule Schedule, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection) (Report, error) {
+	report := Report{Year: schedule.Year, Clashes: []Clash{}, RoomProblems: []RoomProblem{}}
+
+	var course_list []courses.Course
+	cursor, err := courses_coll.Find(ctx, bson.M{})
+	if err == nil {
+		err = cursor.All(ctx, &course_list)
+	}
+	if err != nil {
+		return report, fmt.Errorf("error retrieving courses: %w", err)
+	}
+	course_map := make(map[string]courses.Course, len(course_list))
+	for _, course := range course_list {
+		course_map[course.ShortHand] = course
+	}
+
+	var classroom_list []classrooms.Classroom
+	cursor, err = classrooms_coll.Find(ctx, bson.M{})
+	if err == nil {
+		err = cursor.All(ctx, &classroom_list)
+	}
+	if err != nil {
+		return report, fmt.Errorf("error retrieving classrooms: %w", err)
+	}
+	classroom_map := make(map[string]classrooms.Classroom, len(classroom_list))
+	for _, classroom := range classroom_list {
+		classroom_map[classroomKey(classroom.Building, classroom.Room_number)] = classroom
+	}
+
+	var terms []string
+	for _, term := range schedule.Terms {
+		terms = append(terms, term.Term)
+		term_blocks, err := blocks.ForTerm(ctx, blocks_coll, term.Term)
+		if err != nil {
+			return report, fmt.Errorf("error retrieving blocks: %w", err)
+		}
+		report.Clashes = append(report.Clashes, FindClashes(term, term_blocks)...)
+		report.RoomProblems = append(report.RoomProblems, FindRoomProblems(term, course_map, classroom_map)...)
+	}
+	report.Term = strings.Join(terms, ",")
+	report.count()
+
+	switch {
+	case len(report.RoomProblems) > 0:
+		return report, ErrRoomProblems
+	case report.Hard > 0:
+		return report, ErrHardClashes
+	}
+	return report, nil
+}
+
+// count totals the hard and soft problems of the report
+func (report *Report) count() {
+	report.Hard = len(report.RoomProblems)
+	report.Soft = 0
+	for _, clash := range report.Clashes {
+		if clash.Constraint == blocks.Hard {
+			report.Hard++
+		} else {
+			report.Soft++
+		}
+	}
+}
+
+// GetClashes - lists the clashing sections of courses that share a block in a draft schedule
+func GetClashes(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, blocks_coll *mongo.Collection) {
+	logger.Info("GetClashes function called.")
+
+	schedule, ok := findTermSchedule(w, r, collection)
+	if !ok {
+		return
+	}
+
+	clashes := []Clash{}
+	for _, term := range schedule.Terms {
+		term_blocks, err := blocks.ForTerm(r.Context(), blocks_coll, term.Term)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error retrieving blocks: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving blocks.")
+			return
+		}
+		clashes = append(clashes, FindClashes(term, term_blocks)...)
+	}
+
+	report := Report{Year: schedule.Year, Term: schedule.Terms[0].Term, Clashes: clashes}
+	report.count()
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ValidateSchedule - lists every clash and classroom problem in a draft schedule
+func ValidateSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, blocks_coll *mongo.Collection, courses_coll *mongo.Collection, classrooms_coll *mongo.Collection) {
+	logger.Info("ValidateSchedule function called.")
+
+	schedule, ok := findTermSchedule(w, r, collection)
+	if !ok {
+		return
+	}
+
+	report, err := validateSchedule(r.Context(), schedule, blocks_coll, courses_coll, classrooms_coll)
+	if err != nil && !errors.Is(err, ErrHardClashes) && !errors.Is(err, ErrRoomProblems) {
+		logger.Error(fmt.Errorf("Error validating schedule: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error validating schedule.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// findTermSchedule finds the draft schedule for the year and term in the URL, keeping only
+// that term. It writes the error response and returns false if it can't.
+func findTermSchedule(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) (Schedule, bool) {
+	vars := mux.Vars(r)
+	year, err := strconv.Atoi(vars["year"])
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid year"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid year")
+		return Schedule{}, false
+	}
+	term := terms.Code(vars["term"])
+
+	var schedule Schedule
+	err = collection.FindOne(context.TODO(), bson.M{"year": year, "terms.term": term}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			logger.Error(fmt.Errorf("schedule not found"), http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Schedule not found")
+		} else {
+			logger.Error(fmt.Errorf("Error finding schedule: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Internal server error")
+		}
+		return Schedule{}, false
+	}
+
+	var terms []Term
+	for _, scheduleTerm := range schedule.Terms {
+		if scheduleTerm.Term == term {
+			terms = append(terms, scheduleTerm)
+		}
+	}
+	schedule.Terms = terms
+	return schedule, true
+}
@@ -0,0 +1,253 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// Result types
+const (
+	Course    = "course"
+	User      = "user"
+	Classroom = "classroom"
+)
+
+// Number of results returned when the request doesn't give a limit, and the most it can ask for
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+)
+
+// Result is one match, ID is what the resource's own endpoint is keyed on
+type Result struct {
+	Type  string  `json:"type"`
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+// Response lists the results best first
+type Response struct {
+	Query   string   `json:"query"`
+	Results []Result `json:"results"`
+}
+
+// field is a searchable value of a document and how much a match on it counts
+type field struct {
+	value  string
+	weight float64
+}
+
+// query is a parsed search. Every term must match the start of a word in one of the fields,
+// compact is the whole query without spaces so "csc 225" also finds "CSC225".
+type query struct {
+	terms   []string
+	compact string
+}
+
+func parseQuery(q string) query {
+	terms := strings.Fields(strings.ToLower(q))
+	return query{terms: terms, compact: strings.Join(terms, "")}
+}
+
+// filter finds the documents where any term starts a word of one of the fields. It only
+// narrows down the candidates, score decides what matches.
+func (q query) filter(fields ...string) bson.M {
+	var or bson.A
+	for _, name := range fields {
+		for _, term := range q.terms {
+			or = append(or, bson.M{name: bson.M{"$regex": `\b` + regexp.QuoteMeta(term), "$options": "i"}})
+		}
+		or = append(or, bson.M{name: bson.M{"$regex": `\b` + regexp.QuoteMeta(q.compact), "$options": "i"}})
+	}
+	return bson.M{"$or": or}
+}
+
+// score ranks how well the fields match, 0 means they don't
+func (q query) score(fields ...field) float64 {
+	return higher(scoreTerms(q.terms, fields), scoreTerms([]string{q.compact}, fields))
+}
+
+func scoreTerms(terms []string, fields []field) float64 {
+	total := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for _, f := range fields {
+			best = higher(best, matchTerm(term, strings.ToLower(f.value))*f.weight)
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total
+}
+
+// matchTerm scores an exact match above a prefix of the value, above a prefix of a later word
+func matchTerm(term string, value string) float64 {
+	switch {
+	case value == term:
+		return 3
+	case strings.HasPrefix(value, term):
+		return 2
+	}
+	for _, word := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == '-' || r == '@' || r == '.' }) {
+		if strings.HasPrefix(word, term) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// higher returns the larger of two scores
+func higher(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Search - finds courses by shorthand or name, classrooms by building and room and, for
+// admins, users by name, username or email
+func Search(w http.ResponseWriter, r *http.Request, courses_coll *mongo.Collection, users_coll *mongo.Collection, classrooms_coll *mongo.Collection) {
+	logger.Info("Search function called.")
+
+	values := r.URL.Query()
+	q := parseQuery(values.Get("q"))
+	if len(q.terms) == 0 {
+		logger.Error(fmt.Errorf("search query is missing"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Query parameter q is required.")
+		return
+	}
+
+	limit := DefaultLimit
+	if value := values.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MaxLimit {
+			logger.Error(fmt.Errorf("invalid search limit "+value), http.StatusBadRequest)
+			apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, fmt.Sprintf("limit must be between 1 and %d.", MaxLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	types := map[string]bool{Course: true, Classroom: true, User: true}
+	if value := values.Get("types"); value != "" {
+		types = map[string]bool{}
+		for _, name := range strings.Split(value, ",") {
+			types[strings.TrimSpace(name)] = true
+		}
+	}
+	// Only admins can look up users
+	if info, ok := helper.JWTInfoFromContext(r.Context()); !ok || !info.IsAdmin {
+		delete(types, User)
+	}
+
+	results := []Result{}
+	searches := []struct {
+		name string
+		find func(context.Context, *mongo.Collection, query) ([]Result, error)
+		coll *mongo.Collection
+	}{
+		{Course, searchCourses, courses_coll},
+		{Classroom, searchClassrooms, classrooms_coll},
+		{User, searchUsers, users_coll},
+	}
+	for _, s := range searches {
+		if !types[s.name] {
+			continue
+		}
+		found, err := s.find(r.Context(), s.coll, q)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error searching "+s.name+"s: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error searching "+s.name+"s.")
+			return
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Response{Query: values.Get("q"), Results: results})
+}
+
+func searchCourses(ctx context.Context, collection *mongo.Collection, q query) ([]Result, error) {
+	var found []courses.Course
+	cursor, err := collection.Find(ctx, q.filter("shorthand", "name"))
+	if err == nil {
+		err = cursor.All(ctx, &found)
+	}
+
+	var results []Result
+	for _, course := range found {
+		// Shorthands are what people usually type, so they count for more
+		score := q.score(field{course.ShortHand, 2}, field{course.Name, 1})
+		if score > 0 {
+			results = append(results, Result{Type: Course, ID: course.ShortHand, Title: course.Name, Score: score})
+		}
+	}
+	return results, err
+}
+
+func searchClassrooms(ctx context.Context, collection *mongo.Collection, q query) ([]Result, error) {
+	var found []classrooms.Classroom
+	cursor, err := collection.Find(ctx, q.filter("building", "room"))
+	if err == nil {
+		err = cursor.All(ctx, &found)
+	}
+
+	var results []Result
+	for _, classroom := range found {
+		name := classroom.Building + " " + classroom.Room_number
+		score := q.score(field{classroom.Building, 1}, field{classroom.Room_number, 1}, field{name, 1})
+		if score > 0 {
+			title := fmt.Sprintf("%s, %s for %d", name, classroom.RoomType(), classroom.Capacity)
+			results = append(results, Result{Type: Classroom, ID: name, Title: title, Score: score})
+		}
+	}
+	return results, err
+}
+
+func searchUsers(ctx context.Context, collection *mongo.Collection, q query) ([]Result, error) {
+	var found []users.User
+	opts := options.Find().SetProjection(bson.M{"username": 1, "name": 1, "email": 1})
+	cursor, err := collection.Find(ctx, q.filter("username", "name", "email"), opts)
+	if err == nil {
+		err = cursor.All(ctx, &found)
+	}
+
+	var results []Result
+	for _, user := range found {
+		score := q.score(field{user.Username, 1}, field{user.Name, 1}, field{user.Email, 1})
+		if score > 0 {
+			results = append(results, Result{Type: User, ID: user.Username, Title: user.Name, Score: score})
+		}
+	}
+	return results, err
+}
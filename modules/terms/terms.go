@@ -0,0 +1,385 @@
+package terms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// DefaultCodes are the term codes accepted for a year that has no terms configured
+var DefaultCodes = []string{"fall", "spring", "summer"}
+
+// WeekDays are the day codes used for teaching days, matching the schedule's section days
+var WeekDays = []string{"M", "T", "W", "R", "F", "S", "U"}
+
+// ErrUnknownTerm is returned by Check when the term is not configured for the year
+var ErrUnknownTerm = errors.New("unknown term")
+
+// Term represents an academic term and its calendar
+type Term struct {
+	Year          int         `json:"year" bson:"year"`
+	Term          string      `json:"term" bson:"term"`
+	Name          string      `json:"name" bson:"name"`
+	StartDate     Date        `json:"start_date" bson:"start_date"`
+	EndDate       Date        `json:"end_date" bson:"end_date"`
+	ReadingBreaks []DateRange `json:"reading_breaks" bson:"reading_breaks"`
+	Holidays      []Holiday   `json:"holidays" bson:"holidays"`
+	TeachingDays  []string    `json:"teaching_days" bson:"teaching_days"`
+}
+
+// DateRange is an inclusive range of dates, such as a reading break
+type DateRange struct {
+	Start Date `json:"start" bson:"start"`
+	End   Date `json:"end" bson:"end"`
+}
+
+// Holiday is a single day without classes
+type Holiday struct {
+	Date Date   `json:"date" bson:"date"`
+	Name string `json:"name" bson:"name"`
+}
+
+// Date is a calendar day, written as "2006-01-02" in JSON and stored as a UTC date in MongoDB
+type Date struct {
+	time.Time
+}
+
+// MarshalJSON writes the date as "2006-01-02"
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format("2006-01-02"))
+}
+
+// UnmarshalJSON reads a date written as "2006-01-02"
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	d.Time = parsed
+	return nil
+}
+
+// MarshalBSONValue stores the date as a BSON datetime
+func (d Date) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(d.Time.UTC())
+}
+
+// UnmarshalBSONValue reads the date from a BSON datetime
+func (d *Date) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var value time.Time
+	err := bson.RawValue{Type: t, Value: data}.Unmarshal(&value)
+	if err != nil {
+		return err
+	}
+	d.Time = value.UTC()
+	return nil
+}
+
+var termCodeFormat = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Validate checks the term's fields and returns every problem found
+func (t *Term) Validate() []string {
+	var problems []string
+
+	t.Term = Code(t.Term)
+	if !termCodeFormat.MatchString(t.Term) {
+		problems = append(problems, "term must be a lower case code such as \"fall\"")
+	}
+	if t.Year < 1900 || t.Year > 9999 {
+		problems = append(problems, "year must be a four digit year")
+	}
+	if t.StartDate.IsZero() || t.EndDate.IsZero() {
+		problems = append(problems, "start_date and end_date are required")
+	} else if !t.StartDate.Before(t.EndDate.Time) {
+		problems = append(problems, "start_date must be before end_date")
+	}
+
+	for _, reading := range t.ReadingBreaks {
+		if reading.End.Before(reading.Start.Time) {
+			problems = append(problems, "reading break "+reading.Start.Format("2006-01-02")+" ends before it starts")
+		}
+		if !t.contains(reading.Start) || !t.contains(reading.End) {
+			problems = append(problems, "reading break "+reading.Start.Format("2006-01-02")+" is outside the term")
+		}
+	}
+	for _, holiday := range t.Holidays {
+		if !t.contains(holiday.Date) {
+			problems = append(problems, "holiday "+holiday.Date.Format("2006-01-02")+" is outside the term")
+		}
+	}
+
+	if len(t.TeachingDays) == 0 {
+		t.TeachingDays = []string{"M", "T", "W", "R", "F"}
+	}
+	for _, day := range t.TeachingDays {
+		if !contains(WeekDays, day) {
+			problems = append(problems, "teaching day "+day+" must be one of "+strings.Join(WeekDays, ", "))
+		}
+	}
+
+	return problems
+}
+
+// contains checks if the date falls within the term
+func (t *Term) contains(date Date) bool {
+	return !date.Before(t.StartDate.Time) && !date.After(t.EndDate.Time)
+}
+
+// IsTeachingDay checks if classes are held on the given date
+func (t *Term) IsTeachingDay(date time.Time) bool {
+	day := Date{time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)}
+	if !t.contains(day) {
+		return false
+	}
+	if !contains(t.TeachingDays, WeekDays[(int(day.Weekday())+6)%7]) {
+		return false
+	}
+	for _, reading := range t.ReadingBreaks {
+		if !day.Before(reading.Start.Time) && !day.After(reading.End.Time) {
+			return false
+		}
+	}
+	for _, holiday := range t.Holidays {
+		if day.Equal(holiday.Date.Time) {
+			return false
+		}
+	}
+	return true
+}
+
+// Code - returns the code a term is stored under, so /schedules/2024/Fall and
+// /schedules/2024/fall are the same schedule
+func Code(term string) string {
+	return strings.ToLower(strings.TrimSpace(term))
+}
+
+// Check returns ErrUnknownTerm unless the term is configured for the year. A year without
+// any configured terms accepts the DefaultCodes, so existing schedules keep working.
+func Check(ctx context.Context, collection *mongo.Collection, year int, term string) error {
+	term = Code(term)
+
+	count, err := collection.CountDocuments(ctx, bson.M{"year": year, "term": term})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	configured, err := collection.CountDocuments(ctx, bson.M{"year": year})
+	if err != nil {
+		return err
+	}
+	if configured == 0 && contains(DefaultCodes, term) {
+		return nil
+	}
+	return ErrUnknownTerm
+}
+
+// CreateTerm handles the creation of a new term
+func CreateTerm(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("CreateTerm function called.")
+
+	// Parse request body into Term struct
+	var newTerm Term
+	err := json.NewDecoder(r.Body).Decode(&newTerm)
+	if err != nil {
+		// If there is an error decoding the request body,
+		// log the error and return a bad request response
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	problems := newTerm.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid term: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid term.", apierror.Problems(problems)...)
+		return
+	}
+
+	// Insert the term, the unique index rejects a duplicate year and term
+	_, err = collection.InsertOne(context.TODO(), newTerm)
+	if mongo.IsDuplicateKeyError(err) {
+		logger.Error(fmt.Errorf("term already exists"), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Term already exists.")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error inserting term: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error inserting term.")
+		return
+	}
+
+	// Send a response with the created term
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newTerm)
+}
+
+// termList - the filters and sort keys of GET /terms, in calendar order by default
+var termList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "year", Field: "year", Kind: helper.Int},
+		{Param: "term", Field: "term", Kind: helper.Exact},
+	},
+	Sorts:       map[string]string{"year": "year", "start_date": "start_date"},
+	DefaultSort: "start_date",
+}
+
+// GetTerms retrieves all terms, optionally only those of the ?year= query parameter
+func GetTerms(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetTerms function called.")
+
+	query, err := helper.ParseListQuery(r, termList)
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
+		return
+	}
+
+	page, err := helper.FindPage[Term](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving terms: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving terms.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetTerm retrieves a term by year and term code
+func GetTerm(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("GetTerm function called.")
+
+	filter, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	var term Term
+	err := collection.FindOne(context.TODO(), filter).Decode(&term)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			logger.Error(fmt.Errorf("term not found"), http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Term not found.")
+		} else {
+			logger.Error(fmt.Errorf("Error getting term: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting term.")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(term)
+}
+
+// UpdateTerm replaces the calendar of an existing term, the year and term code cannot change
+func UpdateTerm(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("UpdateTerm function called.")
+
+	filter, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	var updatedTerm Term
+	err := json.NewDecoder(r.Body).Decode(&updatedTerm)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	// The year and term come from the URL
+	updatedTerm.Year = filter["year"].(int)
+	updatedTerm.Term = filter["term"].(string)
+
+	problems := updatedTerm.Validate()
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid term: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid term.", apierror.Problems(problems)...)
+		return
+	}
+
+	res, err := collection.ReplaceOne(context.TODO(), filter, updatedTerm)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating term: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating term.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		logger.Error(fmt.Errorf("term not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Term not found.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedTerm)
+}
+
+// DeleteTerm handles the deletion of a term
+func DeleteTerm(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("DeleteTerm function called.")
+
+	filter, ok := termFilter(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := collection.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error deleting term: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error deleting term.")
+		return
+	}
+	if res.DeletedCount == 0 {
+		logger.Error(fmt.Errorf("term not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "Term not found.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// termFilter builds the filter for the year and term in the URL, writing a bad request response if they are invalid
+func termFilter(w http.ResponseWriter, r *http.Request) (bson.M, bool) {
+	vars := mux.Vars(r)
+	year, err := strconv.Atoi(vars["year"])
+	if err != nil {
+		logger.Error(fmt.Errorf("invalid year"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid year")
+		return nil, false
+	}
+	return bson.M{"year": year, "term": Code(vars["term"])}, true
+}
+
+// contains checks if the list holds the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
@@ -14,24 +13,47 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// HashPassword - returns the bcrypt hash that is stored for a password
+func HashPassword(pw string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
 func verify_pw(hash, pw string) bool {
 
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw))
 	return err == nil
 }
 
-// SignIn: Does Sign In process, and returns jwt token and user role
-func SignIn(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+// SignIn: Does Sign In process, and returns jwt token and user role. The guard slows down and
+// locks out repeated failures.
+func SignIn(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, guard *LoginGuard, jwtSecret string) {
 	logger.Info("Signin function called.")
 
 	// Define an empty slice to store the users
 	var signInReq User
 	err := json.NewDecoder(r.Body).Decode(&signInReq)
 	if err != nil {
-		http.Error(w, "Error while decoding User Object"+err.Error(), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+
+	// The password isn't even checked while the username or address has to wait
+	wait, err := guard.blocked(r, signInReq.Username)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error checking failed logins: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking failed logins.")
+		return
+	}
+	if wait > 0 {
+		writeBlocked(w, r, wait)
 		return
 	}
 
@@ -41,21 +63,32 @@ func SignIn(w http.ResponseWriter, r *http.Request, collection *mongo.Collection
 	err = collection.FindOne(context.TODO(), filter).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			logger.Error(fmt.Errorf("username or password incorrect"), http.StatusNotFound)
-			http.Error(w, "Username or password incorrect.", http.StatusNotFound)
+			guard.failed(r, signInReq.Username)
+			logger.Error(fmt.Errorf("username or password incorrect"), http.StatusUnauthorized)
+			apierror.Write(w, r, http.StatusUnauthorized, apierror.InvalidCredentials, "Username or password incorrect.")
 			return
 		}
-		http.Error(w, "Error while searching for user"+err.Error(), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while searching for user.")
 		return
 	}
 
 	pw_correct := verify_pw(user.Password, signInReq.Password)
 	if signInReq.Username != user.Username || !pw_correct {
-		logger.Error(fmt.Errorf("username or Password Incorrect"), http.StatusNotFound)
-		http.Error(w, "Username or password incorrect.", http.StatusNotFound)
+		guard.failed(r, signInReq.Username)
+		logger.Error(fmt.Errorf("username or Password Incorrect"), http.StatusUnauthorized)
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.InvalidCredentials, "Username or password incorrect.")
 		return
 	}
 
+	// Only tell someone who knows the password that the account is deactivated
+	if user.Deactivated() {
+		logger.Error(fmt.Errorf("deactivated user "+user.Username+" tried to sign in"), http.StatusForbidden)
+		apierror.Write(w, r, http.StatusForbidden, apierror.AccountDeactivated, "This account has been deactivated.")
+		return
+	}
+
+	guard.succeeded(r, user.Username)
+
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 	claims["expiry"] = time.Now().Add(48 * time.Hour)
@@ -68,12 +101,12 @@ func SignIn(w http.ResponseWriter, r *http.Request, collection *mongo.Collection
 		claims["userType"] = "prof"
 	}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokenString, err := token.SignedString([]byte(jwtSecret))
 	if err != nil {
-		http.Error(w, "Error while making JWT token"+err.Error(), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error while making JWT token.")
 		return
 	}
-	helper.VerifyJWT(tokenString)
+	helper.VerifyJWT(tokenString, jwtSecret)
 
 	// Send a response with the retrieved users
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,109 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// Active - the filter matching the users that haven't been deactivated
+var Active = bson.M{"deactivated_at": nil}
+
+// Deactivated - reports whether the user has been deactivated
+func (u *User) Deactivated() bool {
+	return u.DeactivatedAt != nil
+}
+
+// DeactivateUser - deactivates a user instead of deleting it, so the name stays in the schedules
+// that were approved with it. Deactivating a user twice keeps the first date.
+func DeactivateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("DeactivateUser function called.")
+
+	// Extract the user username from the URL path
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+
+	// Held until the user is deactivated, so a concurrent revoke or deactivation can't also
+	// count them as the remaining admin
+	unlock, err := lockAdmins(r.Context(), collection)
+	if err != nil {
+		writeRoleError(w, r, err)
+		return
+	}
+	defer unlock()
+
+	var user User
+	err = collection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	// An admin locking themselves out would leave nobody to undo it
+	if info, ok := helper.JWTInfoFromContext(r.Context()); ok && info.Email != "" && info.Email == user.Email {
+		logger.Error(fmt.Errorf("user "+username+" tried to deactivate themselves"), http.StatusForbidden)
+		apierror.Write(w, r, http.StatusForbidden, apierror.Forbidden, "You can't deactivate your own account.")
+		return
+	}
+	err = checkOtherAdmins(collection, user)
+	if err != nil {
+		writeRoleError(w, r, err)
+		return
+	}
+
+	filter := bson.M{"username": username, "deactivated_at": nil}
+	update := bson.M{"$set": bson.M{"deactivated_at": time.Now().UTC()}, "$inc": bson.M{etag.Field: 1}}
+	setDeactivation(w, r, collection, username, filter, update)
+}
+
+// ReactivateUser - lets a deactivated user sign in and be scheduled again
+func ReactivateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("ReactivateUser function called.")
+
+	// Extract the user username from the URL path
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+
+	filter := bson.M{"username": username, "deactivated_at": bson.M{"$ne": nil}}
+	update := bson.M{"$unset": bson.M{"deactivated_at": ""}, "$inc": bson.M{etag.Field: 1}}
+	setDeactivation(w, r, collection, username, filter, update)
+}
+
+// setDeactivation applies the update to the user if it matches the filter, a user that doesn't
+// is already in the wanted state and is left as it is
+func setDeactivation(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, username string, filter bson.M, update bson.M) {
+	var user User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		err = collection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
+	}
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	etag.Set(w, user.Version)
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeFindError sends the response for an error finding a user
+func writeFindError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == mongo.ErrNoDocuments {
+		logger.Error(fmt.Errorf("user not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "User not found.")
+		return
+	}
+	logger.Error(fmt.Errorf("Error querying collection: "+err.Error()), http.StatusInternalServerError)
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error querying collection.")
+}
@@ -0,0 +1,183 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+)
+
+// LoginAttempts is the record of the recent failed logins of a username or client address
+type LoginAttempts struct {
+	Key          string    `bson:"key"`
+	Failures     int       `bson:"failures"`
+	LastFailure  time.Time `bson:"last_failure"`
+	BlockedUntil time.Time `bson:"blocked_until"`
+	Locked       bool      `bson:"locked"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
+
+// LoginGuard limits failed logins per username and per client address. The failures are kept in
+// Mongo so every instance of the server sees the same ones.
+type LoginGuard struct {
+	Attempts   *mongo.Collection
+	Events     *mongo.Collection
+	Policy     config.LoginConfig
+	TrustProxy bool
+}
+
+// keys returns the attempt keys of the username and the address the request came from
+func (g *LoginGuard) keys(r *http.Request, username string) (string, string) {
+	return "user:" + username, "ip:" + helper.ClientIP(r, g.TrustProxy)
+}
+
+// blocked returns how long until the username may try to log in again from the address of the
+// request, 0 if it may now
+func (g *LoginGuard) blocked(r *http.Request, username string) (time.Duration, error) {
+	userKey, ipKey := g.keys(r, username)
+	now := time.Now().UTC()
+	filter := bson.M{"key": bson.M{"$in": []string{userKey, ipKey}}, "blocked_until": bson.M{"$gt": now}}
+	cursor, err := g.Attempts.Find(r.Context(), filter)
+	if err != nil {
+		return 0, err
+	}
+	var found []LoginAttempts
+	err = cursor.All(r.Context(), &found)
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, attempts := range found {
+		if remaining := attempts.BlockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// fail counts a failed login against the key and blocks it for the backoff that follows, or
+// locks it out once it reaches its maximum failures
+func (g *LoginGuard) fail(r *http.Request, key string, maxFailures int) error {
+	now := time.Now().UTC()
+	cutoff := now.Add(-g.Policy.FailureWindow.Duration)
+
+	// Failures older than the window no longer count, done in one update so instances don't race
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"key": key,
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$lt": bson.A{"$last_failure", cutoff}},
+			1,
+			bson.M{"$add": bson.A{"$failures", 1}},
+		}},
+		"last_failure": now,
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var attempts LoginAttempts
+	err := g.Attempts.FindOneAndUpdate(r.Context(), bson.M{"key": key}, update, opts).Decode(&attempts)
+	if err != nil {
+		return err
+	}
+
+	locked := attempts.Failures >= maxFailures
+	delay := g.Policy.Lockout.Duration
+	if !locked {
+		delay = backoff(g.Policy.Backoff.Duration, attempts.Failures, delay)
+	}
+	expires := now.Add(g.Policy.FailureWindow.Duration)
+	if blockedUntil := now.Add(delay); blockedUntil.After(expires) {
+		expires = blockedUntil
+	}
+	_, err = g.Attempts.UpdateOne(r.Context(), bson.M{"key": key}, bson.M{"$set": bson.M{
+		"blocked_until": now.Add(delay),
+		"locked":        locked,
+		"expires_at":    expires,
+	}})
+	if err != nil {
+		return err
+	}
+
+	// Only the failure that reaches the maximum is a new lockout
+	if attempts.Failures == maxFailures {
+		kind, id, _ := strings.Cut(key, ":")
+		logger.Warning("Locked out " + key + " after " + strconv.Itoa(maxFailures) + " failed logins")
+		audit.Security(r, g.Events, "system", "lockout", kind, id)
+	}
+	return nil
+}
+
+// backoff doubles the base delay for every failure after the first, up to the limit
+func backoff(base time.Duration, failures int, limit time.Duration) time.Duration {
+	if failures < 1 || base <= 0 {
+		return 0
+	}
+	delay := float64(base) * math.Pow(2, float64(failures-1))
+	if delay > float64(limit) {
+		return limit
+	}
+	return time.Duration(delay)
+}
+
+// failed counts a failed login against the username and the client address
+func (g *LoginGuard) failed(r *http.Request, username string) {
+	userKey, ipKey := g.keys(r, username)
+	err := g.fail(r, userKey, g.Policy.MaxFailures)
+	if err == nil {
+		err = g.fail(r, ipKey, g.Policy.IPMaxFailures)
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error recording a failed login: "+err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// succeeded forgets the failed logins of the username, those of the address still count
+func (g *LoginGuard) succeeded(r *http.Request, username string) {
+	userKey, _ := g.keys(r, username)
+	_, err := g.Attempts.DeleteOne(r.Context(), bson.M{"key": userKey})
+	if err != nil {
+		logger.Error(fmt.Errorf("Error clearing failed logins: "+err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// writeBlocked sends the response for a login that has to wait
+func writeBlocked(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	logger.Error(fmt.Errorf("login blocked for "+strconv.Itoa(seconds)+"s after failed attempts"), http.StatusTooManyRequests)
+	apierror.Write(w, r, http.StatusTooManyRequests, apierror.TooManyAttempts, "Too many failed logins, try again in "+strconv.Itoa(seconds)+" seconds.")
+}
+
+// UnlockUser - clears the failed logins of a username, lifting a lockout or backoff
+func UnlockUser(w http.ResponseWriter, r *http.Request, guard *LoginGuard) {
+	logger.Info("UnlockUser function called.")
+
+	// Extract the user username from the URL path
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+
+	userKey, _ := guard.keys(r, username)
+	res, err := guard.Attempts.DeleteOne(context.TODO(), bson.M{"key": userKey})
+	if err != nil {
+		logger.Error(fmt.Errorf("Error clearing failed logins: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error clearing failed logins.")
+		return
+	}
+	if res.DeletedCount > 0 {
+		audit.Security(r, guard.Events, audit.Actor(r), "unlock", "user", username)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,262 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/mail"
+)
+
+// ForgotPasswordRequest is the body of POST /password/forgot
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest is the body of POST /password/reset, the token is the one in the emailed link
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ResetToken is a stored password reset. Only the SHA-256 hash of the token is kept, so the
+// collection can't be used to reset passwords.
+type ResetToken struct {
+	TokenHash string    `bson:"token_hash"`
+	Username  string    `bson:"username"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// PasswordResets holds what resetting a password needs besides the users
+type PasswordResets struct {
+	Tokens *mongo.Collection
+	Events *mongo.Collection
+	Mailer mail.Mailer
+	// URL is the page of the frontend the emailed link opens
+	URL string
+	TTL time.Duration
+}
+
+// hashResetToken returns the hash a token is stored under
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// link returns the URL of the frontend page with the token in its query
+func (resets *PasswordResets) link(token string) (string, error) {
+	link, err := url.Parse(resets.URL)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// resetEmails tracks the password reset emails still being sent
+var resetEmails sync.WaitGroup
+
+// WaitForResetEmails blocks until every password reset email being sent has been, or returns
+// the context's error if it is done first
+func WaitForResetEmails(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		resetEmails.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ForgotPassword - emails the user with the address a link to reset their password. The user is
+// looked up and emailed after the response, which is the same whether or not the address belongs
+// to an active user, so neither its status nor its timing tells who has an account.
+func ForgotPassword(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, resets *PasswordResets) {
+	logger.Info("ForgotPassword function called.")
+
+	var request ForgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+	email := strings.TrimSpace(request.Email)
+	if !strings.Contains(email, "@") {
+		logger.Error(fmt.Errorf("invalid password reset request"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid password reset request.", "email must be an email address")
+		return
+	}
+
+	resetEmails.Add(1)
+	go func() {
+		defer resetEmails.Done()
+		// Not the request's context, which ends with the response
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		resets.sendTo(ctx, collection, email)
+	}()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// sendTo emails a reset link to the active user with the address, if there is one. Failures can
+// only be logged, the response has been sent.
+func (resets *PasswordResets) sendTo(ctx context.Context, collection *mongo.Collection, email string) {
+	var user User
+	err := collection.FindOne(ctx, bson.M{"email": email, "deactivated_at": nil}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		logger.Warning("Password reset requested for unknown address " + email)
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error finding user: "+err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	err = resets.send(ctx, user)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error sending the reset email to "+user.Username+": "+err.Error()), http.StatusInternalServerError)
+	}
+}
+
+// send stores a new token for the user, replacing any earlier one, and emails them the link
+func (resets *PasswordResets) send(ctx context.Context, user User) error {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	link, err := resets.link(token)
+	if err != nil {
+		return err
+	}
+	message, err := mail.Compose(user.Email, "password_reset", map[string]string{
+		"Name":     user.Name,
+		"Username": user.Username,
+		"Link":     link,
+		"Expires":  describeDuration(resets.TTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only the latest link works
+	_, err = resets.Tokens.DeleteMany(ctx, bson.M{"username": user.Username})
+	if err != nil {
+		return err
+	}
+	_, err = resets.Tokens.InsertOne(ctx, ResetToken{
+		TokenHash: hashResetToken(token),
+		Username:  user.Username,
+		ExpiresAt: time.Now().UTC().Add(resets.TTL),
+	})
+	if err != nil {
+		return err
+	}
+	return resets.Mailer.Send(message)
+}
+
+// describeDuration writes the duration in words for an email, e.g. "2 hours" or "30 minutes"
+func describeDuration(duration time.Duration) string {
+	count, unit := int(duration.Minutes()), "minute"
+	if duration >= time.Hour && duration%time.Hour == 0 {
+		count, unit = int(duration.Hours()), "hour"
+	}
+	if count != 1 {
+		unit += "s"
+	}
+	return strconv.Itoa(count) + " " + unit
+}
+
+// ResetPassword - sets a new password with a token from ForgotPassword. The token is used up,
+// and the user's failed logins are cleared so a lockout doesn't outlast the reset.
+func ResetPassword(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, resets *PasswordResets, guard *LoginGuard) {
+	logger.Info("ResetPassword function called.")
+
+	var request ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
+		return
+	}
+	var problems []string
+	if request.Token == "" {
+		problems = append(problems, "token is required")
+	}
+	if request.Password == "" {
+		problems = append(problems, "password is required")
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid password reset: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid password reset.", apierror.Problems(problems)...)
+		return
+	}
+
+	hash, err := HashPassword(request.Password)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error hashing the password: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error resetting the password.")
+		return
+	}
+
+	// Deleting the token as it is read makes sure it works only once
+	var stored ResetToken
+	filter := bson.M{"token_hash": hashResetToken(request.Token), "expires_at": bson.M{"$gt": time.Now().UTC()}}
+	err = resets.Tokens.FindOneAndDelete(context.TODO(), filter).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		logger.Error(fmt.Errorf("unknown or expired password reset token"), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidToken, "The reset link is invalid or has expired.")
+		return
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error finding the reset token: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error resetting the password.")
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"password": hash}, "$inc": bson.M{etag.Field: 1}}
+	res, err := collection.UpdateOne(context.TODO(), bson.M{"username": stored.Username, "deactivated_at": nil}, update)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error updating the password: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error resetting the password.")
+		return
+	}
+	// The user was deactivated since the link was sent
+	if res.MatchedCount == 0 {
+		logger.Error(fmt.Errorf("password reset for inactive user "+stored.Username), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidToken, "The reset link is invalid or has expired.")
+		return
+	}
+
+	guard.succeeded(r, stored.Username)
+	audit.Security(r, resets.Events, stored.Username, "password_reset", "user", stored.Username)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,246 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+)
+
+// ErrLastAdmin is returned when a change would leave no active admin
+var ErrLastAdmin = errors.New("the last active admin can't lose admin rights")
+
+// RoleChange is an entry of the audit trail of admin rights
+type RoleChange struct {
+	Username string    `json:"username" bson:"username"`
+	Granted  bool      `json:"granted" bson:"granted"`
+	By       string    `json:"by" bson:"by"`
+	At       time.Time `json:"at" bson:"at"`
+}
+
+// AdminRole is whether a user is an admin and how that came to be
+type AdminRole struct {
+	Username string       `json:"username"`
+	IsAdmin  bool         `json:"is_admin"`
+	Changes  []RoleChange `json:"changes"`
+}
+
+// GetAdminRole - tells whether a user is an admin, with every change of their admin rights
+func GetAdminRole(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, changes_coll *mongo.Collection) {
+	logger.Info("GetAdminRole function called.")
+
+	// Extract the user username from the URL path
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+
+	var user User
+	err := collection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	role := AdminRole{Username: user.Username, IsAdmin: user.IsAdmin, Changes: []RoleChange{}}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: 1}})
+	cursor, err := changes_coll.Find(context.TODO(), bson.M{"username": username}, opts)
+	if err == nil {
+		err = cursor.All(context.TODO(), &role.Changes)
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving role changes: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving role changes.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// GrantAdmin - makes a user an admin
+func GrantAdmin(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, changes_coll *mongo.Collection) {
+	logger.Info("GrantAdmin function called.")
+	setAdmin(w, r, collection, changes_coll, true)
+}
+
+// RevokeAdmin - takes admin rights away from a user, unless they are the last active admin
+func RevokeAdmin(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, changes_coll *mongo.Collection) {
+	logger.Info("RevokeAdmin function called.")
+	setAdmin(w, r, collection, changes_coll, false)
+}
+
+// setAdmin changes the admin rights of the user in the URL and records who did it. Setting them
+// to what they already are changes nothing.
+func setAdmin(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, changes_coll *mongo.Collection, isAdmin bool) {
+	// Extract the user username from the URL path
+	username := strings.TrimSpace(mux.Vars(r)["username"])
+
+	// Revoking is serialised with deactivations, so the remaining admins can't change between
+	// counting them and the update
+	if !isAdmin {
+		unlock, err := lockAdmins(r.Context(), collection)
+		if err != nil {
+			writeRoleError(w, r, err)
+			return
+		}
+		defer unlock()
+	}
+
+	var user User
+	err := collection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		writeFindError(w, r, err)
+		return
+	}
+
+	if user.IsAdmin != isAdmin {
+		if !isAdmin {
+			err = checkOtherAdmins(collection, user)
+			if err != nil {
+				writeRoleError(w, r, err)
+				return
+			}
+		}
+
+		filter := bson.M{"username": username, "isAdmin": user.IsAdmin}
+		update := bson.M{"$set": bson.M{"isAdmin": isAdmin}, "$inc": bson.M{etag.Field: 1}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = collection.FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&user)
+		if err == nil {
+			err = RecordRoleChange(context.TODO(), changes_coll, username, isAdmin, audit.Actor(r))
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			logger.Error(fmt.Errorf("Error changing admin rights: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error changing admin rights.")
+			return
+		}
+		// Otherwise someone else made the same change in the meantime
+	}
+
+	etag.Set(w, user.Version)
+	w.WriteHeader(http.StatusOK)
+}
+
+// adminsLock is the document of the locks collection held by a change that could leave no active admin
+const adminsLock = "admins"
+
+// adminsLockLease is how long the lock is held at most, in case its holder stops before releasing it
+const adminsLockLease = 10 * time.Second
+
+// lockAdmins takes the admins lock, waiting up to adminsLockLease for another holder to release
+// it, and returns the function releasing it. Without it two requests could each count the other's
+// admin and both remove theirs.
+func lockAdmins(ctx context.Context, collection *mongo.Collection) (func(), error) {
+	locks := collection.Database().Collection("locks")
+	owner := primitive.NewObjectID().Hex()
+
+	ctx, cancel := context.WithTimeout(ctx, adminsLockLease)
+	defer cancel()
+	for {
+		// Matches a lock that is free or expired, otherwise the upsert collides with the held one
+		now := time.Now().UTC()
+		_, err := locks.UpdateOne(ctx,
+			bson.M{"_id": adminsLock, "locked_until": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"owner": owner, "locked_until": now.Add(adminsLockLease)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return func() {
+				// Not the request's context, the lock is released even if it was cancelled
+				locks.DeleteOne(context.Background(), bson.M{"_id": adminsLock, "owner": owner})
+			}, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for the admins lock: %w", ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// checkOtherAdmins returns ErrLastAdmin if the user is the only active admin. The caller holds
+// the admins lock.
+func checkOtherAdmins(collection *mongo.Collection, user User) error {
+	if !user.IsAdmin || user.Deactivated() {
+		return nil
+	}
+	filter := bson.M{"isAdmin": true, "deactivated_at": nil, "username": bson.M{"$ne": user.Username}}
+	count, err := collection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// writeRoleError sends the response for an error checking the remaining admins
+func writeRoleError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == ErrLastAdmin {
+		logger.Error(err, http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.LastAdmin, "The last active admin can't lose admin rights.")
+		return
+	}
+	logger.Error(fmt.Errorf("Error checking the remaining admins: "+err.Error()), http.StatusInternalServerError)
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking the remaining admins.")
+}
+
+// RecordRoleChange - adds a change of admin rights to the audit trail
+func RecordRoleChange(ctx context.Context, changes_coll *mongo.Collection, username string, granted bool, by string) error {
+	_, err := changes_coll.InsertOne(ctx, RoleChange{Username: username, Granted: granted, By: by, At: time.Now().UTC()})
+	return err
+}
+
+// BootstrapAdmins - makes the users named in the configuration admins, as long as there is no
+// active admin yet. Once someone can manage roles through the API the configuration is ignored,
+// so revoking one of these users sticks across restarts. Returns the users made admins.
+func BootstrapAdmins(ctx context.Context, collection *mongo.Collection, changes_coll *mongo.Collection, usernames []string) ([]string, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	count, err := collection.CountDocuments(ctx, bson.M{"isAdmin": true, "deactivated_at": nil})
+	if err != nil {
+		return nil, fmt.Errorf("error counting admins: %w", err)
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	var granted []string
+	for _, username := range usernames {
+		filter := bson.M{"username": username, "isAdmin": bson.M{"$ne": true}, "deactivated_at": nil}
+		update := bson.M{"$set": bson.M{"isAdmin": true}, "$inc": bson.M{etag.Field: 1}}
+		res, err := collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return granted, fmt.Errorf("error making %s an admin: %w", username, err)
+		}
+		if res.MatchedCount == 0 {
+			logger.Warning("Admin " + username + " from the configuration is not an active user")
+			continue
+		}
+		err = RecordRoleChange(ctx, changes_coll, username, true, "configuration")
+		if err != nil {
+			return granted, fmt.Errorf("error recording the role change of %s: %w", username, err)
+		}
+		granted = append(granted, username)
+	}
+	return granted, nil
+}
@@ -6,11 +6,17 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/etag"
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
 )
 
 // User represents a user entity
@@ -26,6 +32,8 @@ type User struct {
 	Course_pref   []string              `json:"course_pref" bson:"course_pref"`
 	Time_pref     map[string][][]string `json:"time_pref" bson:"time_pref"`
 	Available     map[string][][]string `json:"available" bson:"available"`
+	Version       int64                 `json:"version" bson:"version"`
+	DeactivatedAt *time.Time            `json:"deactivated_at,omitempty" bson:"deactivated_at,omitempty"`
 }
 
 func CreateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
@@ -38,7 +46,7 @@ func CreateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collec
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.")
 		return
 	}
 
@@ -54,27 +62,34 @@ func CreateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collec
 		// If there is an error querying the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error checking the collection: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error checking the collection.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error checking the collection.")
 		return
 	}
 	if count > 0 {
 		// If the count is greater than 0, indicating an existing user,
 		// return a conflict response
 		logger.Error(fmt.Errorf("username or email already exists"), http.StatusConflict)
-		http.Error(w, "Username or email already exists.", http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Username or email already exists.")
 		return
 	}
 
 	// by default IsAdmin is supposed to be set to false
 	newUser.IsAdmin = false
+	newUser.Version = 1
 
 	// Insert the user into the MongoDB collection
 	_, err = collection.InsertOne(context.TODO(), newUser)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the same user after the check above
+		logger.Error(fmt.Errorf("username or email already exists"), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Username or email already exists.")
+		return
+	}
 	if err != nil {
 		// If there is an error inserting the user into the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error inserting user: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error inserting user.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error inserting user.")
 		return
 	}
 
@@ -86,53 +101,37 @@ func CreateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collec
 	// logger.Info("CreateUser function completed.")
 }
 
+// userList - the filters and sort keys of GET /users
+var userList = helper.ListSpec{
+	Filters: []helper.Filter{
+		{Param: "username", Field: "username", Kind: helper.Exact},
+		{Param: "peng", Field: "peng", Kind: helper.Bool},
+		{Param: "pref_approved", Field: "pref_approved", Kind: helper.Bool},
+	},
+	Sorts:       map[string]string{"username": "username", "name": "name", "max_courses": "max_courses"},
+	DefaultSort: "username",
+}
+
 // GetUsers retrieves all users from the MongoDB collection
 func GetUsers(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("GetUsers function called.")
 
-	// Define an empty slice to store the users
-	var users []User
-
-	// Retrieve all documents from the MongoDB collection
-	cursor, err := collection.Find(context.TODO(), bson.M{})
+	query, err := helper.ParseListQuery(r, userList)
 	if err != nil {
-		// If there is an error retrieving users,
-		// log the error and return an internal server error response
-		logger.Error(fmt.Errorf("Error retrieving users: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error retrieving users.", http.StatusInternalServerError)
+		logger.Error(fmt.Errorf("invalid list query: "+err.Error()), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.BadRequest, "Invalid query: "+err.Error())
 		return
 	}
-	defer cursor.Close(context.TODO())
-
-	// Iterate through the cursor and decode each document into a User struct
-	for cursor.Next(context.TODO()) {
-		var user User
-		err := cursor.Decode(&user)
-		if err != nil {
-			// If there is an error decoding a user document,
-			// log the error and return an internal server error response
-			logger.Error(fmt.Errorf("Error decoding user document: "+err.Error()), http.StatusInternalServerError)
-			http.Error(w, "Error decoding user document.", http.StatusInternalServerError)
-			return
-		}
-		users = append(users, user)
-	}
 
-	// Check for any errors during cursor iteration
-	if err := cursor.Err(); err != nil {
-		// If there is an error iterating through the cursor,
-		// log the error and return an internal server error response
-		logger.Error(fmt.Errorf("Error iterating cursor: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error iterating cursor.", http.StatusInternalServerError)
+	page, err := helper.FindPage[User](context.TODO(), collection, query, nil)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error retrieving users: "+err.Error()), http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error retrieving users.")
 		return
 	}
 
-	// Send a response with the retrieved users
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(users)
-
-	// Uncomment the follow line for debugging
-	// logger.Info("GetUsers function completed.")
+	json.NewEncoder(w).Encode(page)
 }
 
 // GetUser retrieves a user by username
@@ -140,9 +139,7 @@ func GetUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collectio
 	logger.Info("GetUser function called.")
 
 	// Extract the user username from the URL path
-	path := r.URL.Path
-	username := strings.TrimPrefix(path, "/users/")
-	username = strings.TrimSpace(username)
+	username := strings.TrimSpace(mux.Vars(r)["username"])
 
 	// Retrieve the user from the MongoDB collection
 	filter := bson.M{"username": username}
@@ -153,16 +150,21 @@ func GetUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collectio
 			// If the user is not found,
 			// log the error and return a not found response
 			logger.Error(fmt.Errorf("user not found"), http.StatusNotFound)
-			http.Error(w, "User not found.", http.StatusNotFound)
+			apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "User not found.")
 		} else {
 			// If there is an error retrieving the user,
 			// log the error and return an internal server error response
 			logger.Error(fmt.Errorf("error getting user"), http.StatusInternalServerError)
-			http.Error(w, "Error getting user.", http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error getting user.")
 		}
 		return
 	}
 
+	// The client's copy is still current
+	if etag.NotModified(w, r, user.Version) {
+		return
+	}
+
 	// Send a response with the retrieved user
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
@@ -171,66 +173,140 @@ func GetUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collectio
 	// logger.Info("GetUser function completed.")
 }
 
-// UpdateUser handles updating an existing user
+// userFields - the fields a merge patch may change, the username identifies the user
+var userFields = []string{"email", "password", "name", "peng", "pref_approved", "max_courses", "course_pref", "time_pref", "available"}
+
+// Validate - checks the user fields and returns every problem found
+func (u *User) Validate() []string {
+	var problems []string
+	if strings.TrimSpace(u.Username) == "" {
+		problems = append(problems, "username is required")
+	}
+	if !strings.Contains(u.Email, "@") {
+		problems = append(problems, "email must be an email address")
+	}
+	if u.Max_courses < 0 {
+		problems = append(problems, "max_courses cannot be negative")
+	}
+	return problems
+}
+
+// UpdateUser replaces a user with the request body, the username and password may be left out
 func UpdateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	logger.Info("UpdateUser function called.")
 
+	updateUser(w, r, collection, func(user *User) ([]string, error) {
+		*user = User{Username: user.Username, Password: user.Password, IsAdmin: user.IsAdmin}
+		return nil, helper.DecodeStrict(r.Body, user)
+	})
+}
+
+// PatchUser applies a JSON merge patch from the request body to a user
+func PatchUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	logger.Info("PatchUser function called.")
+
+	updateUser(w, r, collection, func(user *User) ([]string, error) {
+		return helper.MergePatch(user, r.Body, userFields)
+	})
+}
+
+// updateUser applies a change from the request body to the stored user and saves it once it is
+// valid. The change returns the problems with the body, or an error if it can't be decoded.
+func updateUser(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, change func(user *User) ([]string, error)) {
 	// Extract the user username from the URL path
-	path := r.URL.Path
-	username := strings.TrimPrefix(path, "/users/")
-	username = strings.TrimSpace(username)
+	username := strings.TrimSpace(mux.Vars(r)["username"])
 
 	// Check if the user exists in the collection
 	filter := bson.M{"username": username}
-	exists, err := userExists(filter, collection)
+	var stored User
+	err := collection.FindOne(context.TODO(), filter).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		// If the user doesn't exist,
+		// return a not found response
+		logger.Error(fmt.Errorf("user not found"), http.StatusNotFound)
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotFound, "User not found.")
+		return
+	}
 	if err != nil {
 		// If there is an error querying the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error querying collection: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error querying collection.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error querying collection.")
 		return
 	}
-	if !exists {
-		// If the semester doesn't exist,
-		// return a not found response
-		logger.Error(fmt.Errorf("user not found"), http.StatusNotFound)
-		http.Error(w, "User not found.", http.StatusInternalServerError)
+
+	// The client must have read the current version
+	if !etag.CheckIfMatch(w, r, stored.Version) {
 		return
 	}
 
-	// Parse request body into a map
-	var requestBody map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
+	// Apply the request body to the stored user so the result can be validated as a whole
+	updatedUser := stored
+	problems, err := change(&updatedUser)
 	if err != nil {
 		// If there is an error decoding the request body,
 		// log the error and return a bad request response
 		logger.Error(fmt.Errorf("Error decoding the request body: "+err.Error()), http.StatusBadRequest)
-		http.Error(w, "Error decoding the request body.", http.StatusBadRequest)
+		apierror.WriteInvalidBody(w, r, err, "Error decoding the request body.", helper.DecodeProblem(err))
 		return
 	}
-
-	// isAdmin cannot be updated
-	if requestBody["isAdmin"] != nil {
-		logger.Error(fmt.Errorf("isAdmin field cannot be updated"), http.StatusInternalServerError)
-		http.Error(w, "isAdmin field cannot be updated.", http.StatusInternalServerError)
+	if len(problems) == 0 && updatedUser.Username != username {
+		problems = append(problems, "username can't be changed")
+	}
+	if len(problems) == 0 {
+		problems = updatedUser.Validate()
+	}
+	if len(problems) > 0 {
+		logger.Error(fmt.Errorf("invalid user: "+strings.Join(problems, "; ")), http.StatusBadRequest)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, "Invalid user.", apierror.Problems(problems)...)
 		return
 	}
 
-	// Construct the update query
-	update := bson.M{"$set": requestBody}
-
-	// Update the user in the MongoDB collection
-	filter = bson.M{"username": username}
-	_, err = collection.UpdateOne(context.TODO(), filter, update)
+	// Only a new password is hashed, a client sending back what it read keeps the stored hash
+	if updatedUser.Password == "" {
+		updatedUser.Password = stored.Password
+	}
+	if updatedUser.Password != stored.Password {
+		updatedUser.Password, err = HashPassword(updatedUser.Password)
+		if err != nil {
+			logger.Error(fmt.Errorf("Error hashing the password: "+err.Error()), http.StatusInternalServerError)
+			apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating the user.")
+			return
+		}
+	}
+	// Deactivation has its own endpoints
+	updatedUser.DeactivatedAt = stored.DeactivatedAt
+	updatedUser.Version = stored.Version + 1
+
+	// Update the user in the MongoDB collection, unless someone else did since it was read
+	filter = bson.M{"username": username, etag.Field: etag.Current(stored.Version)}
+	res, err := collection.UpdateOne(context.TODO(), filter, bson.M{"$set": updatedUser})
+	if mongo.IsDuplicateKeyError(err) {
+		logger.Error(fmt.Errorf("email already exists"), http.StatusConflict)
+		apierror.Write(w, r, http.StatusConflict, apierror.AlreadyExists, "Email already exists.")
+		return
+	}
 	if err != nil {
 		// If there is an error updating the user in the collection,
 		// log the error and return an internal server error response
 		logger.Error(fmt.Errorf("Error updating the user: "+err.Error()), http.StatusInternalServerError)
-		http.Error(w, "Error updating the user.", http.StatusInternalServerError)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.Internal, "Error updating the user.")
+		return
+	}
+	if res.MatchedCount == 0 {
+		etag.Conflict(w, r)
 		return
 	}
+	if updatedUser.Pref_approved != stored.Pref_approved {
+		event := events.Event{Type: events.PreferencesRejected, Username: username}
+		if updatedUser.Pref_approved {
+			event.Type = events.PreferencesApproved
+		}
+		events.Publish(r.Context(), event)
+	}
 
 	// Send a response indicating successful user update
+	etag.Set(w, updatedUser.Version)
 	w.WriteHeader(http.StatusOK)
 	// fmt.Fprintf(w, "User updated successfully.")
 
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/openapi"
+)
+
+// TestOpenAPIRoutes fails when a route is added to or removed from main.go without updating the spec
+func TestOpenAPIRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	registerRoutes(router)
+
+	registered := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			registered[method+" "+template] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error walking the routes: %v", err)
+	}
+
+	// Versioned routes are served under the base path and at their legacy path
+	described := map[string]bool{}
+	for _, op := range openapi.Operations {
+		described[op.Method+" "+op.Path] = true
+		if !op.Unversioned {
+			described[op.Method+" "+openapi.BasePath+op.Path] = true
+		}
+	}
+
+	var missing, stale []string
+	for route := range registered {
+		if !described[route] {
+			missing = append(missing, route)
+		}
+	}
+	for route := range described {
+		if !registered[route] {
+			stale = append(stale, route)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	for _, route := range missing {
+		t.Errorf("Route %s is not described in modules/openapi/operations.go", route)
+	}
+	for _, route := range stale {
+		t.Errorf("Route %s is described in modules/openapi/operations.go but not registered", route)
+	}
+}
+
+// TestOpenAPIDocument fails when a struct used by the API changes without regenerating the spec
+func TestOpenAPIDocument(t *testing.T) {
+	doc, err := openapi.Generate()
+	if err != nil {
+		t.Fatalf("Error generating the OpenAPI document: %v", err)
+	}
+	if !bytes.Equal(doc, openapi.Document()) {
+		t.Errorf("modules/openapi/openapi.json is out of date, run go generate ./modules/openapi")
+	}
+}
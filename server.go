@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// newServer builds the http.Server for the given handler and configuration
+func newServer(serverConfig config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              serverConfig.Address,
+		Handler:           limitRequestBody(handler, serverConfig.MaxBodyBytes),
+		ReadTimeout:       serverConfig.ReadTimeout.Duration,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout.Duration,
+		WriteTimeout:      serverConfig.WriteTimeout.Duration,
+		IdleTimeout:       serverConfig.IdleTimeout.Duration,
+		MaxHeaderBytes:    serverConfig.MaxHeaderBytes,
+	}
+}
+
+// runServer serves requests until SIGINT or SIGTERM is received, then drains in-flight
+// requests, generation jobs and reset emails and stops the background workers before disconnecting from MongoDB
+func runServer(server *http.Server, serverConfig config.ServerConfig, stopWorkers func(ctx context.Context) error) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if serverConfig.TLSCertFile != "" && serverConfig.TLSKeyFile != "" {
+			logger.Info("Listening with TLS on " + serverConfig.Address)
+			serveErr <- server.ListenAndServeTLS(serverConfig.TLSCertFile, serverConfig.TLSKeyFile)
+		} else {
+			logger.Info("Listening on " + serverConfig.Address)
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	case sig := <-stop:
+		logger.Info("Received " + sig.String() + ", shutting down...")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout.Duration)
+	defer cancel()
+
+	// Stop accepting new connections and wait for in-flight requests
+	err := server.Shutdown(ctx)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error shutting down server: "+err.Error()), http.StatusInternalServerError)
+	}
+
+	// Wait for any schedule generation that is still talking to the algorithm services
+	err = schedules.WaitForGenerationJobs(ctx)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error waiting for generation jobs: "+err.Error()), http.StatusInternalServerError)
+	}
+
+	// And for any password reset email still being sent
+	err = users.WaitForResetEmails(ctx)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error waiting for reset emails: "+err.Error()), http.StatusInternalServerError)
+	}
+
+	// The workers use MongoDB too, so they finish what they are doing first
+	err = stopWorkers(ctx)
+	if err != nil {
+		logger.Error(fmt.Errorf("Error stopping background workers: "+err.Error()), http.StatusInternalServerError)
+	}
+
+	err = client.Disconnect(ctx)
+	if err != nil {
+		return fmt.Errorf("error disconnecting from MongoDB: %w", err)
+	}
+
+	logger.Info("Server stopped.")
+	return nil
+}
+
+// limitRequestBody caps the size of every request body read by the handlers. A body declared
+// too large is refused before it is read, one without a length fails to decode at the limit.
+func limitRequestBody(next http.Handler, maxBytes int64) http.Handler {
+	if maxBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			logger.Error(fmt.Errorf("request body of "+strconv.FormatInt(r.ContentLength, 10)+" bytes refused"), http.StatusRequestEntityTooLarge)
+			apierror.Write(w, r, http.StatusRequestEntityTooLarge, apierror.BodyTooLarge, "Request body is larger than "+strconv.FormatInt(maxBytes, 10)+" bytes.")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+)
+
+func TestLimitRequestBody(t *testing.T) {
+	read := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := limitRequestBody(read, 8)
+
+	for _, test := range []struct {
+		name   string
+		body   string
+		length int64
+		code   int
+	}{
+		{"small enough", "12345678", 8, http.StatusOK},
+		// Refused from its Content-Length, before the handler runs
+		{"declared too large", "123456789", 9, http.StatusRequestEntityTooLarge},
+		// Without a length the handler fails to read past the limit
+		{"chunked too large", "123456789", -1, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/courses", strings.NewReader(test.body))
+		req.ContentLength = test.length
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+		if response.Code != test.code {
+			t.Errorf("Expected response code %d for %s. Got %d", test.code, test.name, response.Code)
+		}
+	}
+}
+
+// TestRunServerDrains checks that a shutdown lets the request in flight finish before the
+// background workers are stopped and MongoDB is disconnected
+func TestRunServerDrains(t *testing.T) {
+	// A client that is connected, lazily, so it can be disconnected
+	saved := client
+	var err error
+	client, err = mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client = saved })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serverConfig := config.Default().Server
+	serverConfig.Address = address
+	serverConfig.ShutdownTimeout = config.Duration{Duration: 5 * time.Second}
+
+	var stoppedAfterRequest atomic.Bool
+	stopWorkers := func(ctx context.Context) error {
+		stoppedAfterRequest.Store(finished.Load())
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runServer(newServer(serverConfig, slow), serverConfig, stopWorkers)
+	}()
+
+	// The server may take a moment to listen
+	code := make(chan int, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			res, err := http.Get("http://" + address + "/")
+			if err == nil {
+				res.Body.Close()
+				code <- res.StatusCode
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		code <- 0
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the request to reach the handler")
+	}
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown. Got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server to shut down")
+	}
+	if got := <-code; got != http.StatusOK {
+		t.Errorf("Expected the request in flight to finish with %d. Got %d", http.StatusOK, got)
+	}
+	if !stoppedAfterRequest.Load() {
+		t.Error("Expected the workers to be stopped after the request finished")
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/helper"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAuditLog(t *testing.T) {
+	db := client.Database("schedule_db")
+	keys := []audit.Key{{Field: "building", Var: "building"}, {Field: "room", Var: "room"}}
+
+	audited := mux.NewRouter()
+	audited.HandleFunc("/classrooms", func(w http.ResponseWriter, r *http.Request) {
+		classrooms.CreateClassroom(w, r, db.Collection("classrooms"))
+	}).Methods(http.MethodPost)
+	audited.HandleFunc("/classrooms/{building}/{room}", func(w http.ResponseWriter, r *http.Request) {
+		classrooms.PatchClassroom(w, r, db.Collection("classrooms"))
+	}).Methods(http.MethodPatch)
+	audited.HandleFunc("/audit", func(w http.ResponseWriter, r *http.Request) {
+		audit.GetEvents(w, r, db.Collection(audit.Collection))
+	}).Methods(http.MethodGet)
+	audited.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(helper.WithAPIKey(r.Context(), "tests")))
+		})
+	})
+	audited.Use(audit.Record(db, map[string]audit.Target{
+		"POST /classrooms":                    {Action: "create", ResourceType: "classroom", Collection: "classrooms", Keys: keys},
+		"PATCH /classrooms/{building}/{room}": {Action: "patch", ResourceType: "classroom", Collection: "classrooms", Keys: keys},
+	}))
+
+	t.Cleanup(func() {
+		db.Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "TestAudit"})
+		db.Collection(audit.Collection).DeleteMany(context.TODO(), bson.M{"resource_id": "TestAudit/1"})
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		audited.ServeHTTP(rr, req)
+		return rr
+	}
+
+	req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBufferString(`{"building": "TestAudit", "room": "1", "capacity": 40}`))
+	serve(req)
+	req, _ = http.NewRequest("PATCH", "/classrooms/TestAudit/1", bytes.NewBufferString(`{"capacity": 60}`))
+	req.Header.Set("If-Match", "*")
+	response := serve(req)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	// Rejected changes aren't recorded
+	req, _ = http.NewRequest("PATCH", "/classrooms/TestAudit/1", bytes.NewBufferString(`{"capacity": "big"}`))
+	req.Header.Set("If-Match", "*")
+	serve(req)
+
+	req, _ = http.NewRequest("GET", "/audit?resource_type=classroom&resource_id=TestAudit/1", nil)
+	response = serve(req)
+	var page struct {
+		Items []audit.Event `json:"items"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &page)
+	if len(page.Items) != 2 {
+		t.Fatalf("Expected 2 events. Got %d\n", len(page.Items))
+	}
+
+	// Newest first
+	patch := page.Items[0]
+	if patch.Action != "patch" || patch.Actor != "apikey:tests" {
+		t.Errorf("Expected a patch by apikey:tests. Got %s by %s\n", patch.Action, patch.Actor)
+	}
+	capacity, ok := patch.Changes["capacity"]
+	if !ok || capacity.Before != float64(40) || capacity.After != float64(60) {
+		t.Errorf("Expected capacity to change from 40 to 60. Got %+v\n", patch.Changes)
+	}
+	if _, ok := page.Items[1].Changes["building"]; !ok {
+		t.Errorf("Expected the create to record every field. Got %+v\n", page.Items[1].Changes)
+	}
+}
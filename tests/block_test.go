@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBlockClashes(t *testing.T) {
+	setupRoutes(router)
+	db := client.Database("schedule_db")
+
+	db.Collection("courses").InsertMany(context.TODO(), []interface{}{
+		bson.M{"shorthand": "TST921"},
+		bson.M{"shorthand": "TST922"},
+	})
+
+	payload := []byte(`{"code": "tst-3-fall", "name": "Test year 3 fall", "term": "fall", "courses": ["TST921", "TST922"], "constraint": "soft"}`)
+	req, _ := http.NewRequest("POST", "/blocks", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	// A block needs courses that exist
+	payload = []byte(`{"code": "tst-bad", "courses": ["TST921", "TST999"]}`)
+	req, _ = http.NewRequest("POST", "/blocks", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+
+	// Both courses meet Monday morning
+	section := func(num string, start string, end string) schedules.Class {
+		return schedules.Class{Num: num, Days: []string{"M", "R"}, StartTime: start, EndTime: end}
+	}
+	db.Collection("draft_schedules").InsertOne(context.TODO(), schedules.Schedule{
+		Year: 2098,
+		Terms: []schedules.Term{{Term: "fall", Courses: []schedules.CourseOffering{
+			{Course: "TST921", Sections: []schedules.Class{section("A01", "08:30", "09:50")}},
+			{Course: "TST922", Sections: []schedules.Class{section("A01", "09:00", "10:20"), section("A02", "10:30", "11:50")}},
+		}}},
+	})
+
+	req, _ = http.NewRequest("GET", "/schedules/2098/fall/clashes", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	var report schedules.Report
+	json.Unmarshal(response.Body.Bytes(), &report)
+	if report.Soft != 1 || report.Hard != 0 {
+		t.Errorf("Expected 1 soft clash. Got %d soft and %d hard\n", report.Soft, report.Hard)
+	}
+
+	// The full validation also checks classrooms, these sections have none
+	req, _ = http.NewRequest("GET", "/schedules/2098/fall/validation", nil)
+	response = executeRequest(req)
+	report = schedules.Report{}
+	json.Unmarshal(response.Body.Bytes(), &report)
+	if report.Soft != 1 || len(report.RoomProblems) != 0 {
+		t.Errorf("Expected 1 soft clash and no room problems. Got %+v\n", report)
+	}
+
+	t.Cleanup(func() {
+		db.Collection("blocks").DeleteMany(context.TODO(), bson.M{"code": bson.M{"$in": bson.A{"tst-3-fall", "tst-bad"}}})
+		db.Collection("courses").DeleteMany(context.TODO(), bson.M{"shorthand": bson.M{"$in": bson.A{"TST921", "TST922"}}})
+		db.Collection("draft_schedules").DeleteMany(context.TODO(), bson.M{"year": 2098})
+	})
+}
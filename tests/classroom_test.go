@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
 	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -178,13 +180,34 @@ func TestUpdateClassroom(t *testing.T) {
 	n2Classroom.Capacity = 98
 	requestBody, _ = json.Marshal(n2Classroom)
 	payload = []byte(requestBody)
+
+	// Updates must name the version they were based on
+	req, _ = http.NewRequest("PUT", "/classrooms/Test6/4", bytes.NewBuffer(payload))
+	response = executeRequest(req)
+	if response.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusPreconditionRequired, response.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/classrooms/Test6/4", nil)
+	response = executeRequest(req)
+	tag := response.Header().Get("ETag")
+
 	req, _ = http.NewRequest("PUT", "/classrooms/Test6/4", bytes.NewBuffer(payload))
+	req.Header.Set("If-Match", tag)
 	response = executeRequest(req)
 
 	if response.Code != http.StatusOK {
 		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
 	}
 
+	// The version read before the update is stale now
+	req, _ = http.NewRequest("PUT", "/classrooms/Test6/4", bytes.NewBuffer(payload))
+	req.Header.Set("If-Match", tag)
+	response = executeRequest(req)
+	if response.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusPreconditionFailed, response.Code)
+	}
+
 	req, _ = http.NewRequest("GET", "/classrooms/Test6/4", bytes.NewBuffer(payload))
 	response = executeRequest(req)
 	if response.Code != http.StatusOK {
@@ -199,8 +222,232 @@ func TestUpdateClassroom(t *testing.T) {
 		t.Errorf("Expected response body to be %d. Got %d\n", 98, getClassroom.Capacity)
 	}
 
+	// A client with the current version doesn't get the body again
+	req, _ = http.NewRequest("GET", "/classrooms/Test6/4", nil)
+	req.Header.Set("If-None-Match", response.Header().Get("ETag"))
+	response = executeRequest(req)
+	if response.Code != http.StatusNotModified {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusNotModified, response.Code)
+	}
+
 	filter := bson.M{"building": nClassroom.Building, "room": nClassroom.Room_number}
 	t.Cleanup(func() {
 		client.Database("schedule_db").Collection("classrooms").DeleteOne(context.TODO(), filter)
 	})
 }
+
+func TestInsertInvalidClassroom(t *testing.T) {
+	setupRoutes(router)
+
+	payload := []byte(`{"building": "Test7", "room": "1", "capacity": 30, "room_type": "gym",
+		"blackouts": [{"days": ["M"], "start_time": "12:00", "end_time": "11:00"}]}`)
+
+	req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+}
+
+func TestRenameClassroom(t *testing.T) {
+	setupRoutes(router)
+
+	// Two rooms can share a building
+	for _, room := range []string{"101", "102"} {
+		payload := []byte(`{"building": "Test8", "room": "` + room + `", "capacity": 40}`)
+		req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		if response.Code != http.StatusOK {
+			t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+	}
+
+	// An update can't move a classroom
+	req, _ := http.NewRequest("PUT", "/classrooms/Test8/101", bytes.NewBuffer([]byte(`{"room": "102"}`)))
+	req.Header.Set("If-Match", "*")
+	response := executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+
+	// Renaming onto an existing room is a conflict
+	req, _ = http.NewRequest("POST", "/classrooms/Test8/101/rename", bytes.NewBuffer([]byte(`{"building": "Test8", "room": "102"}`)))
+	response = executeRequest(req)
+	if response.Code != http.StatusConflict {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusConflict, response.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/classrooms/Test8/101/rename", bytes.NewBuffer([]byte(`{"building": "Test8", "room": "103"}`)))
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/classrooms/Test8/103", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "Test8"})
+	})
+}
+
+func TestRenameClassroomDrafts(t *testing.T) {
+	setupRoutes(router)
+
+	db := client.Database("schedule_db")
+	req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBuffer([]byte(`{"building": "Test9", "room": "201", "capacity": 40}`)))
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	// One section is already in the new room, as if an earlier rename failed after the drafts
+	db.Collection("draft_schedules").InsertOne(context.TODO(), schedules.Schedule{Year: 2096, Terms: []schedules.Term{{Term: "fall", Courses: []schedules.CourseOffering{
+		{Course: "CSC110", Sections: []schedules.Class{{Num: "A01", Building: "Test9", Room: "201"}}},
+		{Course: "CSC111", Sections: []schedules.Class{{Num: "A01", Building: "Test9", Room: "202"}}},
+	}}}, Version: 1})
+	t.Cleanup(func() {
+		db.Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "Test9"})
+		db.Collection("draft_schedules").DeleteMany(context.TODO(), bson.M{"year": 2096})
+		db.Collection(audit.Collection).DeleteMany(context.TODO(), bson.M{"resource_id": "2096/fall"})
+	})
+
+	req, _ = http.NewRequest("POST", "/classrooms/Test9/201/rename", bytes.NewBuffer([]byte(`{"building": "Test9", "room": "202"}`)))
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	var result classrooms.RenameResult
+	json.NewDecoder(response.Body).Decode(&result)
+	if result.DraftSchedules != 1 {
+		t.Errorf("Expected 1 draft schedule updated. Got %d\n", result.DraftSchedules)
+	}
+
+	var draft schedules.Schedule
+	db.Collection("draft_schedules").FindOne(context.TODO(), bson.M{"year": 2096}).Decode(&draft)
+	for _, course := range draft.Terms[0].Courses {
+		if room := course.Sections[0].Room; room != "202" {
+			t.Errorf("Expected %s in room 202. Got %s\n", course.Course, room)
+		}
+	}
+
+	// The draft's change is in the audit log
+	count, _ := db.Collection(audit.Collection).CountDocuments(context.TODO(), bson.M{"action": "rename_classroom", "resource_type": "schedule", "resource_id": "2096/fall"})
+	if count != 1 {
+		t.Errorf("Expected 1 audit event for the draft. Got %d\n", count)
+	}
+
+	// The old name is gone, so renaming it again is not found and changes nothing
+	req, _ = http.NewRequest("POST", "/classrooms/Test9/201/rename", bytes.NewBuffer([]byte(`{"building": "Test9", "room": "203"}`)))
+	if response := executeRequest(req); response.Code != http.StatusNotFound {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusNotFound, response.Code)
+	}
+}
+
+func TestListClassrooms(t *testing.T) {
+	setupRoutes(router)
+
+	for _, classroom := range []classrooms.Classroom{
+		{Building: "TestList", Room_number: "1", Capacity: 50},
+		{Building: "TestList", Room_number: "2", Capacity: 120},
+		{Building: "TestList", Room_number: "3", Capacity: 200},
+	} {
+		payload, _ := json.Marshal(classroom)
+		req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+	}
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "TestList"})
+	})
+
+	var page struct {
+		Items      []classrooms.Classroom `json:"items"`
+		NextCursor string                 `json:"next_cursor"`
+		Total      int64                  `json:"total"`
+	}
+
+	// The largest matching classroom comes first and a cursor points at the next page
+	req, _ := http.NewRequest("GET", "/classrooms?building=TestList&min_capacity=100&sort=-capacity&limit=1", nil)
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	json.NewDecoder(response.Body).Decode(&page)
+	if page.Total != 2 || len(page.Items) != 1 || page.Items[0].Capacity != 200 || page.NextCursor == "" {
+		t.Fatalf("Unexpected first page: %+v\n", page)
+	}
+
+	req, _ = http.NewRequest("GET", "/classrooms?building=TestList&min_capacity=100&sort=-capacity&limit=1&cursor="+page.NextCursor, nil)
+	response = executeRequest(req)
+	page.NextCursor = ""
+	json.NewDecoder(response.Body).Decode(&page)
+	if len(page.Items) != 1 || page.Items[0].Capacity != 120 || page.NextCursor != "" {
+		t.Errorf("Unexpected last page: %+v\n", page)
+	}
+
+	// Unknown sort keys are rejected
+	req, _ = http.NewRequest("GET", "/classrooms?sort=colour", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+}
+
+func TestPatchClassroom(t *testing.T) {
+	setupRoutes(router)
+
+	req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBufferString(`{"building": "Test9", "room": "1", "capacity": 40, "features": ["projector"]}`))
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "Test9"})
+	})
+
+	req, _ = http.NewRequest("GET", "/classrooms/Test9/1", nil)
+	response = executeRequest(req)
+	tag := response.Header().Get("ETag")
+
+	cases := []struct {
+		patch string
+		code  int
+	}{
+		// Fields outside the allow-list, unknown fields and values of the wrong type are rejected
+		{`{"building": "Test10"}`, http.StatusBadRequest},
+		{`{"$set": {"capacity": 1}}`, http.StatusBadRequest},
+		{`{"capacity": "big"}`, http.StatusBadRequest},
+		{`{"capacity": 60, "features": null}`, http.StatusOK},
+	}
+	for _, c := range cases {
+		req, _ = http.NewRequest("PATCH", "/classrooms/Test9/1", bytes.NewBufferString(c.patch))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", tag)
+		response = executeRequest(req)
+		if response.Code != c.code {
+			t.Errorf("Expected response code %d for %s. Got %d\n", c.code, c.patch, response.Code)
+		}
+	}
+
+	// The patch only touched the fields it named
+	req, _ = http.NewRequest("GET", "/classrooms/Test9/1", nil)
+	response = executeRequest(req)
+	var patched classrooms.Classroom
+	json.Unmarshal(response.Body.Bytes(), &patched)
+	if patched.Capacity != 60 || len(patched.Features) != 0 || patched.Room_type != classrooms.Lecture {
+		t.Errorf("Expected capacity 60 without features. Got %+v\n", patched)
+	}
+
+	// A replacement with a field classrooms don't have is rejected
+	req, _ = http.NewRequest("PUT", "/classrooms/Test9/1", bytes.NewBufferString(`{"capacity": 50, "seats": 50}`))
+	req.Header.Set("If-Match", response.Header().Get("ETag"))
+	response = executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+}
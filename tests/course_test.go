@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCreateCourseDefaults(t *testing.T) {
+	setupRoutes(router)
+
+	payload := []byte(`{"shorthand": "TST901", "name": "Test Course", "terms_offered": ["fall"]}`)
+
+	req, _ := http.NewRequest("POST", "/courses", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/courses/TST901", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	var course courses.Course
+	json.Unmarshal(response.Body.Bytes(), &course)
+	if course.Hours.Lecture != 3 || course.Sections != 1 || course.MinEnroll != 5 {
+		t.Errorf("Expected default hours, sections and min_enroll. Got %+v\n", course)
+	}
+
+	// An update that breaks the enrollment limits is rejected
+	payload = []byte(`{"min_enroll": 50, "max_enroll": 20}`)
+	req, _ = http.NewRequest("PUT", "/courses/TST901", bytes.NewBuffer(payload))
+	req.Header.Set("If-Match", response.Header().Get("ETag"))
+	response = executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("courses").DeleteMany(context.TODO(), bson.M{"shorthand": "TST901"})
+	})
+}
+
+func TestCreateInvalidCourse(t *testing.T) {
+	setupRoutes(router)
+
+	payload := []byte(`{"shorthand": "TST902", "hours": {"lecture": 0, "lab": 0, "tutorial": 0}, "sections": 0}`)
+
+	req, _ := http.NewRequest("POST", "/courses", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+}
+
+func TestPrerequisiteGraph(t *testing.T) {
+	setupRoutes(router)
+
+	for _, payload := range []string{
+		`{"shorthand": "TST911", "name": "Test Basics"}`,
+		`{"shorthand": "TST912", "name": "Test Advanced", "prerequisites": [["TST911"]]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/courses", bytes.NewBufferString(payload))
+		response := executeRequest(req)
+		if response.Code != http.StatusOK {
+			t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+	}
+
+	// Unknown prerequisites are rejected
+	req, _ := http.NewRequest("POST", "/courses", bytes.NewBufferString(`{"shorthand": "TST913", "prerequisites": [["TST999"]]}`))
+	response := executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+
+	// Unless the course already had them, like some in the seed data
+	client.Database("schedule_db").Collection("courses").InsertOne(context.TODO(), bson.M{"shorthand": "TST913", "name": "Test Legacy", "prerequisites": bson.A{bson.A{"TST999"}}, "version": 1})
+	req, _ = http.NewRequest("PUT", "/courses/TST913", bytes.NewBufferString(`{"name": "Test Renamed"}`))
+	req.Header.Set("If-Match", "*")
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	// So are cycles
+	req, _ = http.NewRequest("PUT", "/courses/TST911", bytes.NewBufferString(`{"prerequisites": [["TST912"]]}`))
+	req.Header.Set("If-Match", "*")
+	response = executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/courses/TST911/dependents", nil)
+	response = executeRequest(req)
+	var dependents courses.Dependents
+	json.Unmarshal(response.Body.Bytes(), &dependents)
+	if len(dependents.PrerequisiteOf) != 1 || dependents.PrerequisiteOf[0] != "TST912" {
+		t.Errorf("Expected TST912 to depend on TST911. Got %v\n", dependents.PrerequisiteOf)
+	}
+
+	req, _ = http.NewRequest("GET", "/courses/TST912/prerequisites", nil)
+	response = executeRequest(req)
+	var tree courses.PrerequisiteNode
+	json.Unmarshal(response.Body.Bytes(), &tree)
+	if len(tree.Prerequisites) != 1 || tree.Prerequisites[0][0].Course != "TST911" {
+		t.Errorf("Expected TST911 in the prerequisite tree. Got %+v\n", tree)
+	}
+
+	// Deleting a required course needs force
+	req, _ = http.NewRequest("DELETE", "/courses/TST911", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusConflict {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusConflict, response.Code)
+	}
+	req, _ = http.NewRequest("DELETE", "/courses/TST911?force=true", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("courses").DeleteMany(context.TODO(), bson.M{"shorthand": bson.M{"$in": bson.A{"TST911", "TST912", "TST913"}}})
+	})
+}
+
+func TestForceDeleteRequisite(t *testing.T) {
+	setupRoutes(router)
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("courses").DeleteMany(context.TODO(), bson.M{"shorthand": bson.M{"$in": bson.A{"TST920", "TST921", "TST922", "TST923"}}})
+	})
+
+	for _, payload := range []string{
+		`{"shorthand": "TST920", "name": "Test Maths"}`,
+		`{"shorthand": "TST921", "name": "Test Basics"}`,
+		`{"shorthand": "TST922", "name": "Test Basics Online"}`,
+		`{"shorthand": "TST923", "name": "Test Advanced", "prerequisites": [["TST920", "TST921"], ["TST920", "TST922"]]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/courses", bytes.NewBufferString(payload))
+		if response := executeRequest(req); response.Code != http.StatusOK {
+			t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("DELETE", "/courses/TST921?force=true", nil)
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	// The alternative that needed TST921 goes, rather than being left needing TST920 alone
+	req, _ = http.NewRequest("GET", "/courses/TST923", nil)
+	var course courses.Course
+	json.Unmarshal(executeRequest(req).Body.Bytes(), &course)
+	if !reflect.DeepEqual(course.Prerequisites, [][]string{{"TST920", "TST922"}}) {
+		t.Errorf("Expected the prerequisites [[TST920 TST922]]. Got %v\n", course.Prerequisites)
+	}
+}
+
+func TestCourseErrorResponses(t *testing.T) {
+	setupRoutes(router)
+
+	req, _ := http.NewRequest("GET", "/courses/TST999", nil)
+	response := executeRequest(req)
+	if response.Code != http.StatusNotFound {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusNotFound, response.Code)
+	}
+	var notFound apierror.Response
+	json.Unmarshal(response.Body.Bytes(), &notFound)
+	if notFound.Error.Code != apierror.NotFound {
+		t.Errorf("Expected code %s. Got %+v\n", apierror.NotFound, notFound)
+	}
+
+	// Validation problems are listed in the details
+	req, _ = http.NewRequest("POST", "/courses", bytes.NewBufferString(`{"shorthand": "TST", "sections": 0}`))
+	response = executeRequest(req)
+	var invalid apierror.Response
+	json.Unmarshal(response.Body.Bytes(), &invalid)
+	if response.Code != http.StatusBadRequest || invalid.Error.Code != apierror.ValidationFailed || len(invalid.Error.Details) < 2 {
+		t.Errorf("Expected %s with details. Got %d %+v\n", apierror.ValidationFailed, response.Code, invalid)
+	}
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/middleware"
+)
+
+func TestDeprecated(t *testing.T) {
+	deprecated := middleware.Deprecated(middleware.Deprecation{
+		Since:  time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+		Successor: func(path string) string {
+			return "/api/v1" + path
+		},
+	})
+	handler := deprecated(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req, _ := http.NewRequest("GET", "/courses", nil)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	expected := map[string]string{
+		"Deprecation": "@1792368000",
+		"Sunset":      "Sat, 01 May 2027 00:00:00 GMT",
+		"Link":        `</api/v1/courses>; rel="successor-version"`,
+	}
+	for header, value := range expected {
+		if got := response.Header().Get(header); got != value {
+			t.Errorf("Expected %s header %q. Got %q\n", header, value, got)
+		}
+	}
+}
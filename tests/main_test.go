@@ -11,11 +11,21 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/SENG-499-Company2-B01/Backend/config"
 	"github.com/SENG-499-Company2-B01/Backend/logger"
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/blocks"
+	"github.com/SENG-499-Company2-B01/Backend/modules/buildings"
 	"github.com/SENG-499-Company2-B01/Backend/modules/classrooms"
 	"github.com/SENG-499-Company2-B01/Backend/modules/courses"
+	"github.com/SENG-499-Company2-B01/Backend/modules/mail"
+	"github.com/SENG-499-Company2-B01/Backend/modules/notifications"
 	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+	"github.com/SENG-499-Company2-B01/Backend/modules/search"
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
 	"github.com/SENG-499-Company2-B01/Backend/modules/users"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -26,6 +36,7 @@ import (
 var router = mux.NewRouter()
 var algs1_api string
 var algs2_api string
+var jwt_secret string
 
 var client *mongo.Client
 
@@ -70,6 +81,7 @@ func init() {
 
 	algs1_api = os.Getenv("ALGS1_API")
 	algs2_api = os.Getenv("ALGS2_API")
+	jwt_secret = os.Getenv("JWT_SECRET")
 
 	// Load the environment variables locally
 	mongohost := os.Getenv("MONGO_LOCAL_HOST")
@@ -110,19 +122,90 @@ func setupRoutes(router *mux.Router) {
 
 	// Handle classroom requests
 	handleClassroomRequests(router)
+	handleBuildingRequests(router)
 
 	// Handle course requests
 	handleCourseRequests(router)
 
+	// Handle term requests
+	handleTermRequests(router)
+	handleBlockRequests(router)
+
 	// Handle schedule requests
 	handleScheduleRequests(router)
+	handleSearchRequests(router)
+}
+
+// loginPolicy has no backoff, so tests can fail logins back to back
+var loginPolicy = config.LoginConfig{
+	MaxFailures:   3,
+	IPMaxFailures: 1000,
+	Lockout:       config.Duration{Duration: time.Minute},
+	FailureWindow: config.Duration{Duration: time.Minute},
+}
+
+func loginGuard() *users.LoginGuard {
+	return &users.LoginGuard{
+		Attempts: client.Database("schedule_db").Collection("login_attempts"),
+		Events:   client.Database("schedule_db").Collection(audit.Collection),
+		Policy:   loginPolicy,
+	}
+}
+
+// mailbox keeps the messages sent by the routes instead of sending them
+type mailbox struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (box *mailbox) Send(message mail.Message) error {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	box.messages = append(box.messages, message)
+	return nil
+}
+
+// last returns the latest message sent to the address
+func (box *mailbox) last(to string) (mail.Message, bool) {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+	for i := len(box.messages) - 1; i >= 0; i-- {
+		if box.messages[i].To == to {
+			return box.messages[i], true
+		}
+	}
+	return mail.Message{}, false
+}
+
+var sentMail = &mailbox{}
+
+func passwordResets() *users.PasswordResets {
+	return &users.PasswordResets{
+		Tokens: client.Database("schedule_db").Collection("password_resets"),
+		Events: client.Database("schedule_db").Collection(audit.Collection),
+		Mailer: sentMail,
+		URL:    "http://localhost:3000/reset-password",
+		TTL:    time.Hour,
+	}
 }
 
 func handleUserRequests(router *mux.Router) {
 
 	// AUTHENTICATION
 	router.HandleFunc("/signin", func(w http.ResponseWriter, r *http.Request) {
-		users.SignIn(w, r, client.Database("schedule_db").Collection("users"))
+		users.SignIn(w, r, client.Database("schedule_db").Collection("users"), loginGuard(), jwt_secret)
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/password/forgot", func(w http.ResponseWriter, r *http.Request) {
+		users.ForgotPassword(w, r, client.Database("schedule_db").Collection("users"), passwordResets())
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/password/reset", func(w http.ResponseWriter, r *http.Request) {
+		users.ResetPassword(w, r, client.Database("schedule_db").Collection("users"), passwordResets(), loginGuard())
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/users/{username}/unlock", func(w http.ResponseWriter, r *http.Request) {
+		users.UnlockUser(w, r, loginGuard())
 	}).Methods(http.MethodPost)
 
 	router.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
@@ -136,6 +219,50 @@ func handleUserRequests(router *mux.Router) {
 	router.HandleFunc("/users/{username}", func(w http.ResponseWriter, r *http.Request) {
 		users.UpdateUser(w, r, client.Database("schedule_db").Collection("users"))
 	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/users/{username}", func(w http.ResponseWriter, r *http.Request) {
+		users.PatchUser(w, r, client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodPatch)
+
+	router.HandleFunc("/users/{username}", func(w http.ResponseWriter, r *http.Request) {
+		users.DeactivateUser(w, r, client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/users/{username}/admin", func(w http.ResponseWriter, r *http.Request) {
+		users.GetAdminRole(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("role_changes"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/users/{username}/admin", func(w http.ResponseWriter, r *http.Request) {
+		users.GrantAdmin(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("role_changes"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/users/{username}/admin", func(w http.ResponseWriter, r *http.Request) {
+		users.RevokeAdmin(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("role_changes"))
+	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/users/{username}/reactivate", func(w http.ResponseWriter, r *http.Request) {
+		users.ReactivateUser(w, r, client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/users/{username}/notifications", func(w http.ResponseWriter, r *http.Request) {
+		notifications.GetNotifications(w, r, client.Database("schedule_db").Collection(notifications.InboxCollection))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/users/{username}/notifications/{id}/read", func(w http.ResponseWriter, r *http.Request) {
+		notifications.MarkNotificationRead(w, r, client.Database("schedule_db").Collection(notifications.InboxCollection))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/users/{username}/notification-preferences", func(w http.ResponseWriter, r *http.Request) {
+		notifications.GetPreferences(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection(notifications.PreferencesCollection))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/users/{username}/notification-preferences", func(w http.ResponseWriter, r *http.Request) {
+		notifications.UpdatePreferences(w, r, client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection(notifications.PreferencesCollection))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/users/deactivated/sections", func(w http.ResponseWriter, r *http.Request) {
+		schedules.GetOrphanedSections(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("users"))
+	}).Methods(http.MethodGet)
 }
 
 func handleClassroomRequests(router *mux.Router) {
@@ -156,9 +283,40 @@ func handleClassroomRequests(router *mux.Router) {
 		classrooms.UpdateClassroom(w, r, client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodPut)
 
+	router.HandleFunc("/classrooms/{building}/{room}", func(w http.ResponseWriter, r *http.Request) {
+		classrooms.PatchClassroom(w, r, client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodPatch)
+
 	router.HandleFunc("/classrooms/{building}/{room}", func(w http.ResponseWriter, r *http.Request) {
 		classrooms.DeleteClassroom(w, r, client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/classrooms/{building}/{room}/rename", func(w http.ResponseWriter, r *http.Request) {
+		classrooms.RenameClassroom(w, r, client.Database("schedule_db").Collection("classrooms"), client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection(audit.Collection))
+	}).Methods(http.MethodPost)
+}
+
+func handleBuildingRequests(router *mux.Router) {
+	// Buildings CRUD Operations
+	router.HandleFunc("/buildings", func(w http.ResponseWriter, r *http.Request) {
+		buildings.CreateBuilding(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/buildings", func(w http.ResponseWriter, r *http.Request) {
+		buildings.GetBuildings(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/buildings/{code}", func(w http.ResponseWriter, r *http.Request) {
+		buildings.GetBuilding(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/buildings/{code}", func(w http.ResponseWriter, r *http.Request) {
+		buildings.UpdateBuilding(w, r, client.Database("schedule_db").Collection("buildings"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/buildings/{code}", func(w http.ResponseWriter, r *http.Request) {
+		buildings.DeleteBuilding(w, r, client.Database("schedule_db").Collection("buildings"), client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodDelete)
 }
 
 func handleCourseRequests(router *mux.Router) {
@@ -182,6 +340,56 @@ func handleCourseRequests(router *mux.Router) {
 	router.HandleFunc("/courses/{courseShortHand}", func(w http.ResponseWriter, r *http.Request) {
 		courses.UpdateCourse(w, r, client.Database("schedule_db").Collection("courses"))
 	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/courses/{courseShortHand}", func(w http.ResponseWriter, r *http.Request) {
+		courses.PatchCourse(w, r, client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodPatch)
+
+	router.HandleFunc("/courses/{courseShortHand}/prerequisites", func(w http.ResponseWriter, r *http.Request) {
+		courses.GetPrerequisites(w, r, client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/courses/{courseShortHand}/dependents", func(w http.ResponseWriter, r *http.Request) {
+		courses.GetDependents(w, r, client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodGet)
+}
+
+func handleTermRequests(router *mux.Router) {
+	// Terms CRUD Operations
+	router.HandleFunc("/terms", func(w http.ResponseWriter, r *http.Request) {
+		terms.CreateTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/terms/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		terms.GetTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/terms/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		terms.DeleteTerm(w, r, client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodDelete)
+}
+
+func handleBlockRequests(router *mux.Router) {
+	// Blocks CRUD Operations
+	router.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
+		blocks.CreateBlock(w, r, client.Database("schedule_db").Collection("blocks"), client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
+		blocks.GetBlocks(w, r, client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/blocks/{code}", func(w http.ResponseWriter, r *http.Request) {
+		blocks.GetBlock(w, r, client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/blocks/{code}", func(w http.ResponseWriter, r *http.Request) {
+		blocks.UpdateBlock(w, r, client.Database("schedule_db").Collection("blocks"), client.Database("schedule_db").Collection("courses"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/blocks/{code}", func(w http.ResponseWriter, r *http.Request) {
+		blocks.DeleteBlock(w, r, client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodDelete)
 }
 
 func handleScheduleRequests(router *mux.Router) {
@@ -190,11 +398,35 @@ func handleScheduleRequests(router *mux.Router) {
 		schedules.GetSchedules(w, r, client.Database("schedule_db").Collection("draft_schedules"))
 	}).Methods(http.MethodGet)
 
+	router.HandleFunc("/schedules/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		schedules.GetSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("terms"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/schedules/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		schedules.UpdateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("terms"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/schedules/{year}/{term}", func(w http.ResponseWriter, r *http.Request) {
+		schedules.PatchSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("terms"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodPatch)
+
+	router.HandleFunc("/schedules/{year}/{term}/clashes", func(w http.ResponseWriter, r *http.Request) {
+		schedules.GetClashes(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("blocks"))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/schedules/{year}/{term}/validation", func(w http.ResponseWriter, r *http.Request) {
+		schedules.ValidateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodGet)
+
 	// Schedules Generation Endpoints
 	router.HandleFunc("/schedules/{year}/{term}/generate", func(w http.ResponseWriter, r *http.Request) {
 		schedules.GenerateSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"),
 			client.Database("schedule_db").Collection("users"), client.Database("schedule_db").Collection("courses"),
-			client.Database("schedule_db").Collection("classrooms"), algs1_api, algs2_api)
+			client.Database("schedule_db").Collection("classrooms"), client.Database("schedule_db").Collection("terms"),
+			client.Database("schedule_db").Collection("blocks"), algs1_api, algs2_api)
 	}).Methods(http.MethodPost)
 
 	// Previous Schedule Operations
@@ -203,10 +435,18 @@ func handleScheduleRequests(router *mux.Router) {
 	}).Methods(http.MethodGet)
 
 	router.HandleFunc("/schedules/prev", func(w http.ResponseWriter, r *http.Request) {
-		schedules.ApproveSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("previous_schedules"))
+		schedules.ApproveSchedule(w, r, client.Database("schedule_db").Collection("draft_schedules"), client.Database("schedule_db").Collection("previous_schedules"), client.Database("schedule_db").Collection("terms"), client.Database("schedule_db").Collection("blocks"),
+			client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("classrooms"))
 	}).Methods(http.MethodPost)
 }
 
+func handleSearchRequests(router *mux.Router) {
+	router.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		search.Search(w, r, client.Database("schedule_db").Collection("courses"), client.Database("schedule_db").Collection("users"),
+			client.Database("schedule_db").Collection("classrooms"))
+	}).Methods(http.MethodGet)
+}
+
 func main() {
 	// // Example Logging messages
 	// logger.Info("This is an info message")
@@ -217,8 +457,10 @@ func main() {
 
 	handleUserRequests(router)
 	handleClassroomRequests(router)
+	handleBuildingRequests(router)
 	handleCourseRequests(router)
 	handleScheduleRequests(router)
+	handleSearchRequests(router)
 
 }
 
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/migrations"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRunMigrations(t *testing.T) {
+	db := client.Database("schedule_db")
+
+	_, err := migrations.Run(context.TODO(), db)
+	if err != nil {
+		t.Fatalf("Expected migrations to apply. Got %s\n", err)
+	}
+
+	// Running again should not apply anything
+	ran, err := migrations.Run(context.TODO(), db)
+	if err != nil {
+		t.Fatalf("Expected migrations to apply. Got %s\n", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("Expected no pending migrations. Got %d\n", len(ran))
+	}
+
+	statuses, err := migrations.Status(context.TODO(), db)
+	if err != nil {
+		t.Fatalf("Expected migration status. Got %s\n", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("Expected migration %d to be applied\n", status.Version)
+		}
+	}
+
+	// The classroom index should reject a duplicate building and room
+	classroom := bson.M{"building": "TestMigration", "room": "1", "capacity": 10}
+	_, err = db.Collection("classrooms").InsertOne(context.TODO(), classroom)
+	if err != nil {
+		t.Fatalf("Expected classroom to be inserted. Got %s\n", err)
+	}
+	_, err = db.Collection("classrooms").InsertOne(context.TODO(), classroom)
+	if err == nil {
+		t.Errorf("Expected duplicate classroom to be rejected\n")
+	}
+
+	// Users without an email address, "-" in the seed data, are left out of the email index
+	for _, username := range []string{"migrationtest1", "migrationtest2"} {
+		_, err = db.Collection("users").InsertOne(context.TODO(), bson.M{"username": username, "email": "-", "password": "x"})
+		if err != nil {
+			t.Errorf("Expected users without an email to be inserted. Got %s\n", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		db.Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "TestMigration"})
+		db.Collection("users").DeleteMany(context.TODO(), bson.M{"username": bson.M{"$in": bson.A{"migrationtest1", "migrationtest2"}}})
+	})
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/events"
+	"github.com/SENG-499-Company2-B01/Backend/modules/notifications"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+)
+
+// failingChannel fails every delivery, like a mail server that is down
+type failingChannel struct{}
+
+func (failingChannel) Deliver(ctx context.Context, delivery notifications.Delivery) error {
+	return errors.New("mail server down")
+}
+
+func TestNotifications(t *testing.T) {
+	setupRoutes(router)
+
+	db := client.Database("schedule_db")
+	users_coll := db.Collection("users")
+	outbox := db.Collection(notifications.OutboxCollection)
+	inbox := db.Collection(notifications.InboxCollection)
+	preferences := db.Collection(notifications.PreferencesCollection)
+
+	users_coll.InsertOne(context.TODO(), users.User{Username: "notifytest", Name: "Notify Test", Email: "notifytest@uvic.ca", Version: 1})
+	t.Cleanup(func() {
+		users_coll.DeleteOne(context.TODO(), bson.M{"username": "notifytest"})
+		outbox.DeleteMany(context.TODO(), bson.M{"username": "notifytest"})
+		inbox.DeleteMany(context.TODO(), bson.M{"username": "notifytest"})
+		preferences.DeleteMany(context.TODO(), bson.M{"username": "notifytest"})
+	})
+
+	// Unknown channels are refused, types left out keep every channel
+	req, _ := http.NewRequest("PUT", "/users/notifytest/notification-preferences", bytes.NewBufferString(`{"channels": {"schedule_generated": ["pigeon"]}}`))
+	if response := executeRequest(req); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+	req, _ = http.NewRequest("PUT", "/users/notifytest/notification-preferences", bytes.NewBufferString(`{"channels": {"schedule_generated": []}}`))
+	response := executeRequest(req)
+	var chosen notifications.Preferences
+	json.Unmarshal(response.Body.Bytes(), &chosen)
+	if response.Code != http.StatusOK || len(chosen.Channels[events.ScheduleGenerated]) != 0 || len(chosen.Channels[events.SectionReassigned]) != len(notifications.ChannelNames) {
+		t.Errorf("Expected schedule_generated to be turned off. Got %d, %+v\n", response.Code, chosen)
+	}
+
+	service := &notifications.Service{
+		Users:        users_coll,
+		Outbox:       outbox,
+		Preferences:  preferences,
+		Channels:     map[string]notifications.Channel{notifications.Inbox: notifications.InboxChannel{Inbox: inbox}, notifications.Email: failingChannel{}},
+		MaxAttempts:  2,
+		RetryBackoff: time.Millisecond,
+	}
+	service.Handle(context.TODO(), events.Event{Type: events.ScheduleGenerated, Year: 2024, Term: "fall", Professors: []string{"notifytest"}})
+	service.Handle(context.TODO(), events.Event{
+		Type: events.SectionReassigned, Year: 2024, Term: "fall", Course: "SENG 499", Section: "A01",
+		From: "Someone Else", To: "Notify Test", Professors: []string{"Someone Else", "Notify Test"},
+	})
+
+	// The email is tried again after the backoff, then given up on
+	for i := 0; i < 2; i++ {
+		service.DeliverDue(context.TODO())
+		time.Sleep(10 * time.Millisecond)
+	}
+	for channel, status := range map[string]string{notifications.Inbox: notifications.Sent, notifications.Email: notifications.Failed} {
+		var delivery notifications.Delivery
+		err := outbox.FindOne(context.TODO(), bson.M{"username": "notifytest", "channel": channel}).Decode(&delivery)
+		if err != nil || delivery.Status != status {
+			t.Errorf("Expected the %s delivery to be %s. Got %+v, %v\n", channel, status, delivery, err)
+		}
+	}
+
+	unread := func() []notifications.Notification {
+		req, _ := http.NewRequest("GET", "/users/notifytest/notifications?read=false", nil)
+		response := executeRequest(req)
+		var page struct {
+			Items []notifications.Notification `json:"items"`
+		}
+		json.Unmarshal(response.Body.Bytes(), &page)
+		return page.Items
+	}
+	items := unread()
+	if len(items) != 1 || items[0].Type != events.SectionReassigned || !strings.Contains(items[0].Body, "You have been assigned SENG 499 A01") {
+		t.Fatalf("Expected only the reassignment in the inbox. Got %+v\n", items)
+	}
+
+	req, _ = http.NewRequest("POST", "/users/notifytest/notifications/"+items[0].ID+"/read", nil)
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	if items := unread(); len(items) != 0 {
+		t.Errorf("Expected no unread notifications. Got %+v\n", items)
+	}
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/apierror"
+	"github.com/SENG-499-Company2-B01/Backend/modules/openapi"
+	"github.com/gorilla/mux"
+)
+
+func TestValidateRequests(t *testing.T) {
+	validated := mux.NewRouter()
+	validated.Use(openapi.ValidateRequests)
+	validated.HandleFunc("/classrooms", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods(http.MethodPost)
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"building": "ECS", "room": "123", "capacity": 40}`, http.StatusOK},
+		{`{"building": "ECS", "room": "123", "capacity": "big"}`, http.StatusBadRequest},
+		{`{"building": "ECS", "room": "123", "seats": 40}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("POST", "/classrooms", bytes.NewBufferString(c.body))
+		response := httptest.NewRecorder()
+		validated.ServeHTTP(response, req)
+		if response.Code != c.code {
+			t.Errorf("Expected response code %d for %s. Got %d\n", c.code, c.body, response.Code)
+			continue
+		}
+		if c.code == http.StatusOK {
+			continue
+		}
+
+		var body apierror.Response
+		json.Unmarshal(response.Body.Bytes(), &body)
+		if body.Error.Code != apierror.ValidationFailed || len(body.Error.Details) == 0 {
+			t.Errorf("Expected a %s error with details for %s. Got %+v\n", apierror.ValidationFailed, c.body, body.Error)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+	"github.com/SENG-499-Company2-B01/Backend/modules/middleware"
+	"github.com/gorilla/mux"
+)
+
+func TestRateLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.General = config.RateLimit{Requests: 3, Period: config.Duration{Duration: time.Hour}, Burst: 3}
+	cfg.RateLimit.Login = config.RateLimit{Requests: 1, Period: config.Duration{Duration: time.Hour}, Burst: 1}
+
+	limited := mux.NewRouter()
+	limited.Use(middleware.NewRateLimiter(cfg).Limit)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	limited.HandleFunc("/login", ok).Methods(http.MethodPost)
+	limited.HandleFunc("/courses", ok).Methods(http.MethodGet)
+
+	send := func(method string, path string, address string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = address + ":1234"
+		response := httptest.NewRecorder()
+		limited.ServeHTTP(response, req)
+		return response
+	}
+
+	// The login route has its own stricter bucket
+	if response := send("POST", "/login", "10.0.0.1"); response.Code != http.StatusOK || response.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected the first login to pass with none remaining. Got %d, %q\n", response.Code, response.Header().Get("RateLimit-Remaining"))
+	}
+	if response := send("POST", "/login", "10.0.0.1"); response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") != "3600" {
+		t.Errorf("Expected the second login to be limited for an hour. Got %d, %q\n", response.Code, response.Header().Get("Retry-After"))
+	}
+
+	// The general bucket still has two requests left, and other addresses have their own
+	for _, expected := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if response := send("GET", "/courses", "10.0.0.1"); response.Code != expected {
+			t.Errorf("Expected response code %d. Got %d\n", expected, response.Code)
+		}
+	}
+	if response := send("GET", "/courses", "10.0.0.2"); response.Code != http.StatusOK || response.Header().Get("RateLimit-Limit") != "3" {
+		t.Errorf("Expected another address to pass. Got %d\n", response.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+)
+
+func TestUpdateScheduleKeepsTerm(t *testing.T) {
+	setupRoutes(router)
+
+	drafts := client.Database("schedule_db").Collection("draft_schedules")
+	drafts.InsertOne(context.TODO(), schedules.Schedule{Year: 2098, Terms: []schedules.Term{{Term: "fall", Courses: []schedules.CourseOffering{}}}, Version: 1})
+	t.Cleanup(func() {
+		drafts.DeleteMany(context.TODO(), bson.M{"year": 2098})
+	})
+
+	cases := []struct {
+		method string
+		body   string
+		code   int
+	}{
+		// Moving the draft to another term would leave nothing at its URL
+		{"PUT", `{"terms": [{"term": "spring", "courses": []}]}`, http.StatusBadRequest},
+		{"PATCH", `{"terms": []}`, http.StatusBadRequest},
+		{"PUT", `{"terms": [{"term": "fall", "courses": []}]}`, http.StatusOK},
+		{"PATCH", `{"terms": [{"term": "fall", "courses": []}]}`, http.StatusOK},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.method, "/schedules/2098/fall", bytes.NewBufferString(c.body))
+		req.Header.Set("If-Match", "*")
+		if c.method == "PATCH" {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		if response := executeRequest(req); response.Code != c.code {
+			t.Errorf("Expected response code %d for %s %s. Got %d\n", c.code, c.method, c.body, response.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/schedules/2098/fall", nil)
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Errorf("Expected the draft at its URL. Got %d\n", response.Code)
+	}
+}
+
+func TestScheduleTermCase(t *testing.T) {
+	setupRoutes(router)
+
+	drafts := client.Database("schedule_db").Collection("draft_schedules")
+	drafts.InsertOne(context.TODO(), schedules.Schedule{Year: 2097, Terms: []schedules.Term{{Term: "fall", Courses: []schedules.CourseOffering{}}}, Version: 1})
+	t.Cleanup(func() {
+		drafts.DeleteMany(context.TODO(), bson.M{"year": 2097})
+	})
+
+	// Fall is the same term as fall, not a draft of its own
+	req, _ := http.NewRequest("PUT", "/schedules/2097/Fall", bytes.NewBufferString(`{"terms": [{"term": "fall", "courses": []}]}`))
+	req.Header.Set("If-Match", "*")
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	req, _ = http.NewRequest("GET", "/schedules/2097/FALL", nil)
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	if count, _ := drafts.CountDocuments(context.TODO(), bson.M{"year": 2097}); count != 1 {
+		t.Errorf("Expected one draft for 2097. Got %d\n", count)
+	}
+}
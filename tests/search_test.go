@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/search"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSearch(t *testing.T) {
+	setupRoutes(router)
+
+	req, _ := http.NewRequest("POST", "/courses", bytes.NewBufferString(`{"shorthand": "TST950", "name": "Quuxable Data Structures"}`))
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	req, _ = http.NewRequest("POST", "/classrooms", bytes.NewBufferString(`{"building": "TestSearch", "room": "150", "capacity": 40}`))
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	t.Cleanup(func() {
+		client.Database("schedule_db").Collection("courses").DeleteMany(context.TODO(), bson.M{"shorthand": "TST950"})
+		client.Database("schedule_db").Collection("classrooms").DeleteMany(context.TODO(), bson.M{"building": "TestSearch"})
+	})
+
+	cases := []struct {
+		query string
+		typ   string
+		id    string
+	}{
+		{"quuxable data str", search.Course, "TST950"},
+		{"tst 950", search.Course, "TST950"},
+		{"testsearch 1", search.Classroom, "TestSearch 150"},
+	}
+	for _, c := range cases {
+		req, _ = http.NewRequest("GET", "/search?"+url.Values{"q": {c.query}}.Encode(), nil)
+		response = executeRequest(req)
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+
+		var found search.Response
+		json.Unmarshal(response.Body.Bytes(), &found)
+		if len(found.Results) == 0 || found.Results[0].Type != c.typ || found.Results[0].ID != c.id {
+			t.Errorf("Expected %s %s first for %q. Got %+v\n", c.typ, c.id, c.query, found.Results)
+		}
+	}
+
+	// Users are only searched for admins
+	req, _ = http.NewRequest("GET", "/search?q=a&types=user", nil)
+	response = executeRequest(req)
+	var found search.Response
+	json.Unmarshal(response.Body.Bytes(), &found)
+	if len(found.Results) != 0 {
+		t.Errorf("Expected no user results without an admin token. Got %+v\n", found.Results)
+	}
+
+	req, _ = http.NewRequest("GET", "/search", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+}
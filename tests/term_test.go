@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/terms"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCreateAndGetTerm(t *testing.T) {
+	setupRoutes(router)
+
+	payload := []byte(`{
+		"year": 2099,
+		"term": "winter",
+		"name": "Winter 2099",
+		"start_date": "2099-01-05",
+		"end_date": "2099-04-08",
+		"reading_breaks": [{"start": "2099-02-16", "end": "2099-02-20"}],
+		"holidays": [{"date": "2099-04-03", "name": "Good Friday"}]
+	}`)
+
+	req, _ := http.NewRequest("POST", "/terms", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/terms/2099/winter", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+
+	var term terms.Term
+	json.Unmarshal(response.Body.Bytes(), &term)
+	if len(term.TeachingDays) != 5 {
+		t.Errorf("Expected 5 default teaching days. Got %d\n", len(term.TeachingDays))
+	}
+
+	// Once a year has terms configured, only those terms are accepted
+	collection := client.Database("schedule_db").Collection("terms")
+	if err := terms.Check(context.TODO(), collection, 2099, "winter"); err != nil {
+		t.Errorf("Expected winter 2099 to be valid. Got %s\n", err)
+	}
+	if err := terms.Check(context.TODO(), collection, 2099, "fall"); err != terms.ErrUnknownTerm {
+		t.Errorf("Expected fall 2099 to be unknown. Got %v\n", err)
+	}
+
+	t.Cleanup(func() {
+		collection.DeleteMany(context.TODO(), bson.M{"year": 2099})
+	})
+}
+
+func TestCreateInvalidTerm(t *testing.T) {
+	setupRoutes(router)
+
+	payload := []byte(`{"year": 2099, "term": "fall", "start_date": "2099-12-01", "end_date": "2099-09-01"}`)
+
+	req, _ := http.NewRequest("POST", "/terms", bytes.NewBuffer(payload))
+	response := executeRequest(req)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusBadRequest, response.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/audit"
+	"github.com/SENG-499-Company2-B01/Backend/modules/schedules"
+	"github.com/SENG-499-Company2-B01/Backend/modules/users"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDeactivateUser(t *testing.T) {
+	setupRoutes(router)
+
+	users_coll := client.Database("schedule_db").Collection("users")
+	drafts := client.Database("schedule_db").Collection("draft_schedules")
+
+	hash, _ := users.HashPassword("secret")
+	users_coll.InsertOne(context.TODO(), users.User{Username: "departed", Email: "departed@uvic.ca", Password: hash, Name: "Departed Prof", Version: 1})
+	drafts.InsertOne(context.TODO(), schedules.Schedule{Year: 2098, Terms: []schedules.Term{{Term: "fall", Courses: []schedules.CourseOffering{
+		{Course: "CSC110", Sections: []schedules.Class{{Num: "A01", Professor: "Departed Prof"}, {Num: "A02", Professor: "Someone Else"}}},
+	}}}, Version: 1})
+
+	t.Cleanup(func() {
+		users_coll.DeleteOne(context.TODO(), bson.M{"username": "departed"})
+		drafts.DeleteOne(context.TODO(), bson.M{"year": 2098})
+	})
+
+	signIn := func() int {
+		req, _ := http.NewRequest("POST", "/signin", bytes.NewBufferString(`{"username": "departed", "password": "secret"}`))
+		return executeRequest(req).Code
+	}
+
+	// Deactivating twice is the same as once
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("DELETE", "/users/departed", nil)
+		response := executeRequest(req)
+		if response.Code != http.StatusOK {
+			t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+	}
+	if code := signIn(); code != http.StatusForbidden {
+		t.Errorf("Expected a deactivated user to be refused with %d. Got %d\n", http.StatusForbidden, code)
+	}
+
+	req, _ := http.NewRequest("GET", "/users/deactivated/sections", nil)
+	response := executeRequest(req)
+	var report schedules.OrphanedSections
+	json.Unmarshal(response.Body.Bytes(), &report)
+	found := 0
+	for _, section := range report.Sections {
+		if section.Year == 2098 {
+			found++
+			if section.Section != "A01" || section.Username != "departed" {
+				t.Errorf("Expected A01 taught by departed. Got %+v\n", section)
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("Expected 1 orphaned section. Got %d\n", found)
+	}
+
+	req, _ = http.NewRequest("POST", "/users/departed/reactivate", nil)
+	response = executeRequest(req)
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+	}
+	if code := signIn(); code != http.StatusOK {
+		t.Errorf("Expected a reactivated user to sign in. Got %d\n", code)
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	setupRoutes(router)
+
+	users_coll := client.Database("schedule_db").Collection("users")
+	changes := client.Database("schedule_db").Collection("role_changes")
+
+	// Another admin, so roletest isn't the last one when it loses its rights
+	users_coll.InsertOne(context.TODO(), users.User{Username: "roletest", Email: "roletest@uvic.ca", Version: 1})
+	users_coll.InsertOne(context.TODO(), users.User{Username: "roletest2", Email: "roletest2@uvic.ca", IsAdmin: true, Version: 1})
+	t.Cleanup(func() {
+		users_coll.DeleteMany(context.TODO(), bson.M{"username": bson.M{"$in": []string{"roletest", "roletest2"}}})
+		changes.DeleteMany(context.TODO(), bson.M{"username": "roletest"})
+	})
+
+	for _, method := range []string{"PUT", "DELETE"} {
+		req, _ := http.NewRequest(method, "/users/roletest/admin", nil)
+		response := executeRequest(req)
+		if response.Code != http.StatusOK {
+			t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, response.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/users/roletest/admin", nil)
+	response := executeRequest(req)
+	var role users.AdminRole
+	json.Unmarshal(response.Body.Bytes(), &role)
+	if role.IsAdmin || len(role.Changes) != 2 || !role.Changes[0].Granted || role.Changes[1].Granted {
+		t.Errorf("Expected a grant then a revoke. Got %+v\n", role)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	setupRoutes(router)
+
+	users_coll := client.Database("schedule_db").Collection("users")
+	attempts := client.Database("schedule_db").Collection("login_attempts")
+	events := client.Database("schedule_db").Collection(audit.Collection)
+
+	hash, _ := users.HashPassword("secret")
+	users_coll.InsertOne(context.TODO(), users.User{Username: "locktest", Email: "locktest@uvic.ca", Password: hash, Version: 1})
+	t.Cleanup(func() {
+		users_coll.DeleteOne(context.TODO(), bson.M{"username": "locktest"})
+		attempts.DeleteMany(context.TODO(), bson.M{"key": bson.M{"$in": []string{"user:locktest", "ip:"}}})
+		events.DeleteMany(context.TODO(), bson.M{"resource_id": "locktest"})
+	})
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/signin", bytes.NewBufferString(`{"username": "locktest", "password": "`+password+`"}`))
+		return executeRequest(req)
+	}
+
+	for i := 0; i < 3; i++ {
+		if response := signIn("wrong"); response.Code != http.StatusUnauthorized {
+			t.Errorf("Expected response code %d. Got %d\n", http.StatusUnauthorized, response.Code)
+		}
+	}
+
+	// Locked out, even with the right password
+	response := signIn("secret")
+	if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected response code %d with Retry-After. Got %d\n", http.StatusTooManyRequests, response.Code)
+	}
+	count, _ := events.CountDocuments(context.TODO(), bson.M{"action": "lockout", "resource_id": "locktest"})
+	if count != 1 {
+		t.Errorf("Expected 1 lockout event. Got %d\n", count)
+	}
+
+	req, _ := http.NewRequest("POST", "/users/locktest/unlock", nil)
+	executeRequest(req)
+	if response := signIn("secret"); response.Code != http.StatusOK {
+		t.Errorf("Expected response code %d after unlocking. Got %d\n", http.StatusOK, response.Code)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	setupRoutes(router)
+
+	users_coll := client.Database("schedule_db").Collection("users")
+	resets := client.Database("schedule_db").Collection("password_resets")
+
+	hash, _ := users.HashPassword("forgotten")
+	users_coll.InsertOne(context.TODO(), users.User{Username: "resettest", Email: "resettest@uvic.ca", Password: hash, Version: 1})
+	t.Cleanup(func() {
+		users_coll.DeleteOne(context.TODO(), bson.M{"username": "resettest"})
+		resets.DeleteMany(context.TODO(), bson.M{"username": "resettest"})
+	})
+
+	// Unknown addresses get the same answer, without an email
+	for _, email := range []string{"resettest@uvic.ca", "nobody@uvic.ca"} {
+		req, _ := http.NewRequest("POST", "/password/forgot", bytes.NewBufferString(`{"email": "`+email+`"}`))
+		if response := executeRequest(req); response.Code != http.StatusOK {
+			t.Errorf("Expected response code %d for %s. Got %d\n", http.StatusOK, email, response.Code)
+		}
+	}
+	// The emails are sent after the response
+	users.WaitForResetEmails(context.TODO())
+	if _, ok := sentMail.last("nobody@uvic.ca"); ok {
+		t.Errorf("Expected no email to an unknown address\n")
+	}
+	message, ok := sentMail.last("resettest@uvic.ca")
+	token := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(message.Body)
+	if !ok || token == nil {
+		t.Fatalf("Expected an email with a reset link. Got %+v\n", message)
+	}
+
+	reset := func() int {
+		req, _ := http.NewRequest("POST", "/password/reset", bytes.NewBufferString(`{"token": "`+token[1]+`", "password": "remembered"}`))
+		return executeRequest(req).Code
+	}
+	if code := reset(); code != http.StatusOK {
+		t.Errorf("Expected response code %d. Got %d\n", http.StatusOK, code)
+	}
+	if code := reset(); code != http.StatusBadRequest {
+		t.Errorf("Expected a used token to be refused with %d. Got %d\n", http.StatusBadRequest, code)
+	}
+
+	req, _ := http.NewRequest("POST", "/signin", bytes.NewBufferString(`{"username": "resettest", "password": "remembered"}`))
+	if response := executeRequest(req); response.Code != http.StatusOK {
+		t.Errorf("Expected to sign in with the new password. Got %d\n", response.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/modules/middleware"
+	"github.com/SENG-499-Company2-B01/Backend/modules/openapi"
+)
+
+// currentVersion is the version of the API, every route is mounted under /api/<currentVersion>.
+// A second version would get its own subrouter, with the limiter, access control and other
+// middleware added to it once rather than by registering the older version's routes again.
+const currentVersion = "v1"
+
+// legacyRoutes are the paths from before the API was versioned, served as aliases of v1
+var legacyRoutes = middleware.Deprecation{
+	Since:  time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+	Sunset: time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+	Successor: func(path string) string {
+		return "/api/v1" + path
+	},
+}
+
+// registerV1Routes adds the routes of the first version of the API
+func registerV1Routes(router *mux.Router) {
+	handleUserRequests(router)
+	handleClassroomRequests(router)
+	handleBuildingRequests(router)
+	handleCourseRequests(router)
+	handleTermRequests(router)
+	handleBlockRequests(router)
+	handleScheduleRequests(router)
+	handleSearchRequests(router)
+	handleAuditRequests(router)
+
+	// API documentation
+	router.HandleFunc("/openapi.json", openapi.Spec).Methods(http.MethodGet)
+	router.HandleFunc("/docs", openapi.Docs).Methods(http.MethodGet)
+}
+
+// mountVersions serves the API under its version prefix, and at the legacy paths as an alias
+func mountVersions(router *mux.Router) {
+	// One limiter for both, so a client can't get more requests by switching between them
+	limiter := middleware.NewRateLimiter(cfg)
+
+	api := router.PathPrefix("/api/" + currentVersion).Subrouter()
+	// Added before the routes' own middleware, so it runs before access control
+	api.Use(limiter.Limit)
+	registerV1Routes(api)
+	validateRequests(api)
+	recordChanges(api)
+
+	legacy := router.NewRoute().Subrouter()
+	// First, so refusals by the limiter and access control carry the headers too
+	legacy.Use(middleware.Deprecated(legacyRoutes))
+	legacy.Use(limiter.Limit)
+	registerV1Routes(legacy)
+	validateRequests(legacy)
+	recordChanges(legacy)
+}
+
+// validateRequests checks request bodies against the OpenAPI document if enabled. It runs after
+// access control so the schemas aren't revealed to anonymous callers.
+func validateRequests(router *mux.Router) {
+	if cfg.ValidateRequests {
+		router.Use(openapi.ValidateRequests)
+	}
+}
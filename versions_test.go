@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/SENG-499-Company2-B01/Backend/config"
+)
+
+// TestLegacyRefusalsDeprecated checks that the legacy paths announce their deprecation even when
+// the limiter or access control refuses the request, and that the versioned paths don't
+func TestLegacyRefusalsDeprecated(t *testing.T) {
+	saved := cfg.RateLimit
+	cfg.RateLimit = config.RateLimitConfig{
+		Enabled:  true,
+		General:  config.RateLimit{Requests: 1, Period: config.Duration{Duration: time.Hour}, Burst: 1},
+		Login:    saved.Login,
+		Generate: saved.Generate,
+	}
+	t.Cleanup(func() { cfg.RateLimit = saved })
+
+	router := mux.NewRouter()
+	registerRoutes(router)
+
+	for _, test := range []struct {
+		path       string
+		code       int
+		deprecated bool
+	}{
+		// Without a token, then over the limit
+		{"/courses", http.StatusUnauthorized, true},
+		{"/courses", http.StatusTooManyRequests, true},
+		{"/api/v1/courses", http.StatusTooManyRequests, false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, req)
+
+		if response.Code != test.code {
+			t.Errorf("Expected response code %d for %s. Got %d", test.code, test.path, response.Code)
+		}
+		if deprecated := response.Header().Get("Deprecation") != ""; deprecated != test.deprecated {
+			t.Errorf("Expected the Deprecation header on %s to be %v. Got %q", test.path, test.deprecated, response.Header().Get("Deprecation"))
+		}
+		if test.deprecated && response.Header().Get("Sunset") == "" {
+			t.Errorf("Expected a Sunset header on %s", test.path)
+		}
+	}
+}